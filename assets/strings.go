@@ -6,43 +6,322 @@
 package assets
 
 const (
-	CapNew         = "New"
-	CapOpen        = "Open"
-	CapSave        = "Save"
-	CapPassword    = "Password"
-	CapLocked      = "Locked"
-	CapUnlocked    = "Unlocked"
-	CapLockUnlock  = "Lock/Unlock"
-	CapPwdGet      = "Password for decryption"
-	CapPwdSet      = "Password for encryption"
-	CapPwdEnter    = "Enter password"
-	CapPwdVerify   = "Verify password"
-	CapSaveChanges = "Save changes"
-	CapError       = "Error"
-	CapCopy        = "Copy"
-	CapCut         = "Cut"
-	CapPaste       = "Paste"
+	CapNew                = "New"
+	CapOpen               = "Open"
+	CapSave               = "Save"
+	CapSaveAs             = "Save As..."
+	CapRevert             = "Revert to Saved"
+	CapPassword           = "Password"
+	CapLocked             = "Locked"
+	CapUnlocked           = "Unlocked"
+	CapLockUnlock         = "Lock/Unlock"
+	CapPwdGet             = "Password for decryption"
+	CapPwdSet             = "Password for encryption"
+	CapPwdEnter           = "Enter password"
+	CapPwdVerify          = "Verify password"
+	CapPwdStrength        = "Strength"
+	CapUnlockWith         = "Unlock with"
+	CapKeyfile            = "Keyfile"
+	CapChoose             = "Choose..."
+	CapSaveChanges        = "Save changes"
+	CapError              = "Error"
+	CapCopy               = "Copy"
+	CapCopySensitive      = "Copy as Sensitive"
+	CapCut                = "Cut"
+	CapPaste              = "Paste"
+	CapFileChanged        = "File changed"
+	CapReload             = "Reload"
+	CapSaveCopy           = "Save as Copy..."
+	CapOverwrite          = "Overwrite"
+	CapIgnore             = "Ignore"
+	CapFileLocked         = "File locked"
+	CapOpenReadOnly       = "Open Read-Only"
+	CapBreakLock          = "Break Lock"
+	CapPasswordPolicy     = "Password policy"
+	CapMinLength          = "Minimum length"
+	CapMinClasses         = "Minimum character classes"
+	CapDictionaryCheck    = "Reject commonly used passwords"
+	CapClipboard          = "Clipboard"
+	CapClipboardTimeout   = "Clear after seconds (0 = never)"
+	CapFiles              = "Files"
+	CapCompress           = "Compress documents before encryption"
+	CapTools              = "Tools"
+	CapGeneratorMenu      = "Generate Password..."
+	CapGenerator          = "Generate password"
+	CapGenerate           = "Generate"
+	CapInsert             = "Insert"
+	CapUse                = "Use"
+	CapGenKind            = "Type"
+	CapGenLength          = "Length"
+	CapGenSeparator       = "Separator"
+	CapGenLower           = "Lower case letters (a-z)"
+	CapGenUpper           = "Upper case letters (A-Z)"
+	CapGenDigits          = "Digits (0-9)"
+	CapGenSymbols         = "Symbols (!#$%...)"
+	CapKeySlots           = "Manage Access..."
+	CapKeySlotsTitle      = "Passwords and recipients of this document"
+	CapPwdAdd             = "Add password"
+	CapSlotLabel          = "Name"
+	CapAddPassword        = "Add Password..."
+	CapRevoke             = "Revoke"
+	CapClose              = "Close"
+	CapAddRecipient       = "Add Recipient..."
+	CapRecipientAdd       = "Add recipient"
+	CapPublicKey          = "Public key"
+	CapIdentityPassphrase = "Passphrase for identity"
+	CapNewIdentity        = "New Identity..."
+	CapLoadIdentity       = "Load Identity..."
+	CapCopyPublicKey      = "Copy My Public Key"
+	CapSearchMenu         = "Search Documents..."
+	CapSearchTitle        = "Search documents"
+	CapSearch             = "Search"
+	CapOpenResult         = "Open"
+	CapSearchFor          = "Search for:"
+	CapRegex              = "Regular expression"
+	CapIgnoreCase         = "Ignore case"
+	CapSearchIn           = "Folder:"
+	CapSearchPassword     = "Password for search"
+	CapShowRecords        = "Show Entries"
+	CapMarkdownPreview    = "Markdown Preview"
+	CapSyntax             = "Syntax"
+	CapSyntaxNone         = "None"
+	CapView               = "View"
+	CapLineNumbers        = "Line Numbers"
+	CapWordWrap           = "Word Wrap"
+	CapRecordTitle        = "Title"
+	CapRecordUsername     = "Username"
+	CapRecordPassword     = "Password"
+	CapRecordURL          = "URL"
+	CapRecordNotes        = "Notes"
+	CapNewRecord          = "New Entry"
+	CapDeleteRecord       = "Delete Entry"
+	CapAddField           = "Add Field"
+	CapShow               = "Show"
+	CapHide               = "Hide"
+	CapImport             = "Import..."
+	CapExport             = "Export"
+	CapExportAge          = "age File..."
+	CapExportPgp          = "OpenPGP File (AES-256)..."
+	CapExportPgpTwofish   = "OpenPGP File (Twofish)..."
+	CapExportPassphrase   = "Passphrase for export"
+	CapImportPassphrase   = "Passphrase for import"
+	CapImportPlain        = "Import Plain Text..."
+	CapExportPlain        = "Export Plain Text..."
+	CapShredSource        = "Securely delete the original file once the document has been saved"
+	CapDocumentProperties = "Document Properties..."
+	CapPropertiesTitle    = "Document properties"
+	CapMetaTitle          = "Title"
+	CapMetaAuthor         = "Author"
+	CapMetaTags           = "Tags"
+	CapMetaCreated        = "Created"
+	CapMetaModified       = "Modified"
+	CapMetaProperties     = "Properties"
+	CapPadding            = "Length hiding"
+	CapVault              = "Vault"
+	CapNewVault           = "New Vault..."
+	CapOpenVault          = "Open Vault..."
+	CapCloseVault         = "Close Vault"
+	CapNewEntry           = "New"
+	CapRenameEntry        = "Rename"
+	CapDeleteEntry        = "Delete"
+	CapEntryName          = "Name"
+	CapNewEntryTitle      = "New document"
+	CapRenameEntryTitle   = "Rename document"
+	CapAttachments        = "Attachments..."
+	CapAttachmentsTitle   = "Attachments"
+	CapAddAttachment      = "Add..."
+	CapExtract            = "Extract..."
+	CapRemove             = "Remove"
 
+	TxtReadOnly                 = " [read-only]"
+	TxtGenEntropy               = "Entropy: %.0f bits"
+	TxtSlotEntry                = "%s (%s)"
+	TxtSlotUnnamed              = "Slot %d"
+	TxtSlotLabelHint            = "Names are not encrypted, anyone with the file can read them."
+	TxtSlotCurrent              = " - used to open"
+	TxtRecipientEntry           = "%s (public key %s)"
+	TxtNotSaved                 = "not saved yet"
+	TxtTagsHint                 = "comma separated"
+	TxtPropertiesHint           = "one per line, name: value"
+	TxtAttachmentEntry          = "%s (%s)"
+	TxtSearchStatus             = "%d matches in %d documents"
+	TxtSearchFailed             = "; %d could not be searched"
+	TxtSearchResult             = "%s:%d: %s"
+	TxtSearchContext            = "  %d  %s"
+	TxtSearchMatch              = "> %d  %s"
+	TxtNewRecord                = "New entry"
+	TxtFieldName                = "Name"
+	TxtTOTP                     = "One-time password"
+	TxtTOTPName                 = "%s (%s)"
+	TxtTOTPSeconds              = "%2d s"
+	TxtStatusPosition           = "Ln %d, Col %d"
+	TxtStatusSelected           = " (%d selected)"
+	TxtStatusCounts             = "%d characters, %d words"
+	TxtStatusEncoding           = "UTF-8"
+	TxtStatusFormat             = "Format v%d"
+	TxtStatusModified           = "Modified"
+	TxtStatusReadOnly           = "Read-only"
+	TxtCompressHint             = "The size of a compressed document tells a little about its content."
+	TxtBytes                    = "%d bytes"
+	TxtKiB                      = "%.1f KiB"
+	TxtMiB                      = "%.1f MiB"
 	TxtAboutSimpleTwofishEditor = "Simple Twofish Editor v1.0\n(w) 2024 by Jan Buchholz"
 	TxtAboutDetails             = "Twofish Go port based on Bruce Schneier's\nreference C implementation:\nhttps://www.schneier.com/academic/twofish/"
 	TxtAboutUnison              = "\n\nCredits:\nSimple Twofish Editor has been developed using\nRichard Wilkes' Unison library:\nhttps://github.com/richardwilkes/unison" +
 		"\n\nGopher image created at:\nhttps://gopherize.me/"
 
-	UnnamedFile      = "No name." + FileExtension
-	UnnamedFileNoExt = "No name"
-	AppName          = "Simple Twofish Editor"
-	FileExtension    = "twofish"
-
-	ErrFileOpen        = "Error opening file."
-	ErrFileRead        = "Error reading file."
-	ErrFileWrite       = "Error writing file."
-	ErrNoMatch         = "No Simple Twofish Editor file."
-	ErrCorrupted       = "File appears to be corrupted."
-	ErrDecryptionError = "Decryption failed."
-	ErrEncryptionError = "Encryption failed."
-	ErrUnableToDecrypt = "Unable to decrypt file. Please check password entered and try again."
-	ErrEmptyFile       = "Empty file detected."
-
-	MsgDocumentModified = "Save changes before closing?"
-	MsgWantSave         = "If you don't save, your changes will be lost."
+	UnnamedFile       = "No name." + FileExtension
+	UnnamedFileNoExt  = "No name"
+	AppName           = "Simple Twofish Editor"
+	FileExtension     = "twofish"
+	IdentityExtension = "twofish-id"
+	IdentityFileName  = "identity"
+	AgeExtension      = "age"
+	PgpExtension      = "gpg"
+	PgpExtensions     = "gpg,pgp,asc"
+	PlainExtension    = "txt"
+	VaultExtension    = "twofish-vault"
+	VaultFileName     = "vault.twofish-vault"
+	SyntaxProperty    = "Syntax"
+	WordWrapProperty  = "Word Wrap"
+
+	ErrFileOpen            = "Error opening file."
+	ErrFileRead            = "Error reading file."
+	ErrFileWrite           = "Error writing file."
+	ErrNoMatch             = "No Simple Twofish Editor file."
+	ErrCorrupted           = "File appears to be corrupted."
+	ErrDecryptionError     = "Decryption failed."
+	ErrEncryptionError     = "Encryption failed."
+	ErrUnableToDecrypt     = "Unable to decrypt file. Please check password entered and try again."
+	ErrEmptyFile           = "Empty file detected."
+	ErrKeyfileRequired     = "This file requires a keyfile. Please select the keyfile and try again."
+	ErrPasswordRequired    = "This file requires a password. Please enter the password and try again."
+	ErrNoKeyfileRequired   = "This file does not use a keyfile. Please try again without keyfile."
+	ErrPasswordOnly        = "Files of this format can only be opened with a password."
+	ErrKeyfileRead         = "Error reading keyfile."
+	ErrGeneratorParameters = "Invalid password generator settings."
+	ErrNoKeySlots          = "No document key available, please set a password first."
+	ErrLastKeySlot         = "The last password of a document cannot be revoked."
+	ErrKeySlotsUpdate      = "Unable to update the passwords of the document."
+	ErrIdentityRequired    = "This file can only be opened with an identity. Please load your identity and try again."
+	ErrInvalidRecipient    = "Invalid public key, expected a key starting with age1."
+	ErrDuplicateRecipient  = "This public key is already a recipient of the document."
+	ErrNoIdentity          = "No identity loaded."
+	ErrNoIdentityFile      = "No Simple Twofish Editor identity file."
+	ErrNoContainerV2       = "The file uses an old format without key slots, please save it again."
+	ErrUnknownFormat       = "Unknown file format."
+	ErrPassphraseRequired  = "A passphrase is required."
+	ErrAgeWorkFactor       = "The work factor of this age file is too high."
+	ErrPgpUnsupported      = "This OpenPGP message uses a cipher or packet type that is not supported."
+	ErrPgpPublicKey        = "This OpenPGP message is encrypted to a public key, only symmetric (passphrase) encryption is supported."
+	ErrNoPlainText         = "The file does not contain plain text."
+	ErrShredFile           = "Error securely deleting the original file."
+	ErrNotRegularFile      = "Only regular files can be securely deleted."
+	ErrAttachmentName      = "Invalid attachment name."
+	ErrDuplicateAttachment = "An attachment with this name exists already."
+	ErrNoSuchAttachment    = "No such attachment."
+	ErrAttachment          = "Error updating the attachments."
+	ErrUnknownCompression  = "This file uses an unknown compression method."
+	ErrNoVault             = "No Simple Twofish Editor vault."
+	ErrVaultRequired       = "This document belongs to a vault. Please open the vault and try again."
+	ErrEntryName           = "Please enter a name."
+	ErrDuplicateEntry      = "A document with this name exists already in the vault."
+	ErrNoSuchEntry         = "No such document in the vault."
+	ErrVaultExists         = "This folder contains a vault already."
+	ErrVaultUpdate         = "Unable to update the vault."
+	ErrSearch              = "Unable to search the documents."
+	ErrTOTPInvalid         = "Invalid otpauth URI."
+	ErrTOTPSecret          = "Invalid TOTP secret."
+
+	MsgDocumentModified      = "Save changes before closing?"
+	MsgWantSave              = "If you don't save, your changes will be lost."
+	MsgUseNewPassword        = "Encrypt the copy with a new password?"
+	MsgKeepPassword          = "If you choose No, the current password will be used."
+	MsgRevert                = "Revert to the saved version?"
+	MsgRevertDetail          = "All changes made since the last save will be lost."
+	MsgFileChangedOnDisk     = "The file has been changed by another application."
+	MsgFileNewerOnDisk       = "The file on disk is newer than this document."
+	MsgFileLocked            = "The file is being edited in another instance."
+	MsgLockedDetail          = "Locked by %s on %s since %s."
+	MsgLockStale             = "The instance holding the lock is no longer running."
+	MsgExternalChangeDetail  = "Reload the file, save this document as a copy, or continue with this document."
+	MsgKnownWeakPassword     = "This is a commonly used password and easy to guess."
+	MsgPolicyLength          = "The password must have at least %d characters."
+	MsgPolicyClasses         = "The password must use at least %d character classes (lower, upper, digits, symbols)."
+	MsgRevokeSlot            = "Revoke the selected password?"
+	MsgRevokeSlotDetail      = "The document can no longer be opened with it. The encrypted text is not changed."
+	MsgRevokeCurrentDetail   = "This is the password the document has been opened with. Make sure you know one of the remaining passwords."
+	MsgReplaceIdentity       = "Replace the identity loaded by a new one?"
+	MsgReplaceIdentityDetail = "Documents shared with the current public key need the current identity file to be opened."
+	MsgIdentityCreated       = "Identity created. Your public key has been copied to the clipboard, share it with the people who want to encrypt documents for you:"
+	MsgImportPlain           = "Import an unencrypted text file?"
+	MsgImportPlainDetail     = "The original file stays readable on disk unless it is overwritten and deleted."
+	MsgExportPlain           = "Export the document as unencrypted text?"
+	MsgExportPlainDetail     = "Anyone with access to the exported file can read it. Its location is not remembered."
+	MsgShredNotice           = "The file is overwritten %d times, truncated, renamed and deleted.\n" +
+		"On SSDs, flash drives, journaling or copy-on-write file systems\n" +
+		"and in backups copies of the text may survive."
+	MsgRemoveAttachment       = "Remove the selected attachment?"
+	MsgRemoveAttachmentDetail = "The attachment is no longer part of the document once it has been saved."
+	MsgDeleteEntry            = "Delete the selected document?"
+	MsgDeleteEntryDetail      = "The document is removed from the vault and deleted from disk."
+	MsgDeleteRecord           = "Delete the selected entry?"
+	MsgDeleteRecordDetail     = "The entry and all its fields are removed from the document."
+)
+
+// Command line
+const (
+	CliUsage = "Usage: %s [command] [options]\n" +
+		"Without command the editor is started. Flags must precede file names.\n\nCommands:\n"
+	CliUsageIdentity = "  identity new -o IDENTITY               create an identity file, prints the public key\n" +
+		"  identity show -i IDENTITY              print the public key of an identity"
+	CliUsageRecipients = "  recipients list FILE                   list passwords and recipients of a document\n" +
+		"  recipients add [-i IDENTITY] [-k KEYFILE] [-l NAME] FILE PUBLICKEY...\n" +
+		"  recipients remove [-i IDENTITY] [-k KEYFILE] FILE PUBLICKEY..."
+	CliUsageProperties = "  properties [-i IDENTITY] [-k KEYFILE] FILE\n" +
+		"                                         print title, author, tags, timestamps and properties"
+	CliUsageAttachments = "  attachments list [-i IDENTITY] [-k KEYFILE] FILE\n" +
+		"  attachments extract [-i IDENTITY] [-k KEYFILE] [-o FOLDER] FILE [NAME...]"
+	CliExpectedSubCommand = "expected one of: %s"
+	CliMissingArguments   = "missing arguments, see help"
+	CliMissingOutput      = "missing output file (-o)"
+	CliMissingIdentity    = "missing identity file (-i)"
+	CliPasswordMismatch   = "passwords do not match"
+	CliNoPassword         = "no password entered"
+	CliNoSuchRecipient    = "%s is no recipient of the document"
+	CliFileLocked         = "%s is being edited in another instance. %s"
+	CliPromptPassword     = "Password: "
+	CliPromptPassphrase   = "Passphrase for identity: "
+	CliPromptVerify       = "Verify: "
+	CliFlagIdentity       = "identity file"
+	CliFlagKeyfile        = "keyfile"
+	CliFlagOutput         = "output file"
+	CliFlagOutputFolder   = "output folder"
+	CliNoSuchAttachment   = "%s is no attachment of the document"
+	CliAttachmentEntry    = "%d\t%s\n"
+	CliPropertyEntry      = "%s: %s\n"
+	CliUsageShred         = "  shred [-n PASSES] FILE...              overwrite, truncate, rename and delete plain text files"
+	CliShredNotice        = "note: on SSDs, flash drives, journaling or copy-on-write file systems and in backups copies of the data may survive"
+	CliFlagPasses         = "number of overwrite passes"
+	CliFlagLabel          = "name of the recipient"
+	CliSlotEntry          = "%d\tpassword\t%s\t%s\n"
+	CliRecipientEntry     = "%d\trecipient\t%s\t%s\n"
+	CliUsageSearch        = "  search [-i IDENTITY] [-k KEYFILE] [-e] [-I] [-C LINES] PATTERN FILE|FOLDER...\n" +
+		"                                         search documents in memory, folders of a vault by document name"
+	CliFlagRegex       = "pattern is a regular expression"
+	CliFlagIgnoreCase  = "ignore case"
+	CliFlagContext     = "lines of context around each match"
+	CliSearchFailed    = "%d files could not be searched"
+	CliSearchMatch     = "%s:%d: %s\n"
+	CliSearchContext   = "%s-%d- %s\n"
+	CliSearchSeparator = "--"
+	CliVaultEntryName  = "%s [%s]"
 )
+
+var TxtStrength = [...]string{"Weak", "Fair", "Good", "Strong"}
+
+var TxtGenKinds = [...]string{"Password", "Diceware passphrase"}
+
+var TxtFactors = [...]string{"Password", "Keyfile only", "Password and keyfile"}
+
+var TxtPadding = [...]string{"None", "Padmé (up to 12% larger)", "Power of two", "Blocks of 4 KiB"}
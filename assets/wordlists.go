@@ -0,0 +1,14 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Assets - word lists
+//----------------------------------------------------------------------------------------------------------------------
+
+package assets
+
+import _ "embed"
+
+//go:embed weakpasswords.txt
+var WeakPasswords string
+
+//go:embed eff_large_wordlist.txt
+var DicewareWords string
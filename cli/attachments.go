@@ -0,0 +1,71 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: list and extract the attachments of a document
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+func runAttachments(args []string) error {
+	sub, args, err := subCommand(args, "list", "extract")
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("attachments " + sub)
+	unlock := addUnlockFlags(fs)
+	output := fs.String("o", ".", assets.CliFlagOutputFolder)
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		return errors.New(assets.CliMissingArguments)
+	}
+	payload, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	if _, err = unlock.unlock(payload); err != nil {
+		return err
+	}
+	if sub == "list" {
+		for _, a := range crypto.Attachments() {
+			fmt.Printf(assets.CliAttachmentEntry, a.Size, a.Name)
+		}
+		return nil
+	}
+	return extractAttachments(*output, args[1:])
+}
+
+// All attachments are extracted if no names are given, existing files are never overwritten
+func extractAttachments(folder string, names []string) error {
+	for _, name := range names {
+		if !slices.ContainsFunc(crypto.Attachments(), func(a crypto.AttachmentInfo) bool { return a.Name == name }) {
+			return fmt.Errorf(assets.CliNoSuchAttachment, name)
+		}
+	}
+	for _, a := range crypto.Attachments() {
+		if len(names) > 0 && !slices.Contains(names, a.Name) {
+			continue
+		}
+		data, err := crypto.AttachmentData(a.Index)
+		if err != nil {
+			return err
+		}
+		err = writeNewFile(filepath.Join(folder, crypto.AttachmentName(a.Name)), data)
+		clear(data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line interface, runs instead of the UI if the first argument is a command
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"identity", assets.CliUsageIdentity, runIdentity},
+		{"recipients", assets.CliUsageRecipients, runRecipients},
+		{"properties", assets.CliUsageProperties, runProperties},
+		{"attachments", assets.CliUsageAttachments, runAttachments},
+		{"search", assets.CliUsageSearch, runSearch},
+		{"shred", assets.CliUsageShred, runShred},
+	}
+}
+
+func IsCommand(arg string) bool {
+	return arg == "help" || slices.ContainsFunc(commands, func(c command) bool { return c.name == arg })
+}
+
+// Returns the exit code
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" {
+		usage()
+		return 0
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			if err := c.run(args[1:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(os.Args[0]), err)
+				return 1
+			}
+			return 0
+		}
+	}
+	usage()
+	return 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, assets.CliUsage, filepath.Base(os.Args[0]))
+	for _, c := range commands {
+		fmt.Fprintln(os.Stderr, c.usage)
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// Sub commands like "recipients add", returns the sub command and its arguments
+func subCommand(args []string, valid ...string) (string, []string, error) {
+	if len(args) == 0 || !slices.Contains(valid, args[0]) {
+		return "", nil, fmt.Errorf(assets.CliExpectedSubCommand, strings.Join(valid, ", "))
+	}
+	return args[0], args[1:], nil
+}
+
+// Create a file readable by the owner only, existing files are not replaced
+func writeNewFile(p string, data []byte) error {
+	file, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,64 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: create an identity file, show its public key
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"fmt"
+)
+
+func runIdentity(args []string) error {
+	sub, args, err := subCommand(args, "new", "show")
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("identity " + sub)
+	output := fs.String("o", "", assets.CliFlagOutput)
+	identity := fs.String("i", "", assets.CliFlagIdentity)
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+	switch sub {
+	case "new":
+		if *output == "" {
+			return errors.New(assets.CliMissingOutput)
+		}
+		return newIdentity(*output)
+	default:
+		if *identity == "" {
+			return errors.New(assets.CliMissingIdentity)
+		}
+		if err = loadIdentity(*identity); err != nil {
+			return err
+		}
+		fmt.Println(crypto.IdentityPublicKey())
+		return nil
+	}
+}
+
+func newIdentity(p string) error {
+	passphrase, err := readNewPassword(assets.CliPromptPassphrase, assets.CliPromptVerify)
+	if err != nil {
+		return err
+	}
+	defer clear(passphrase)
+	publicKey, err := crypto.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+	data, err := crypto.EncryptIdentity(passphrase)
+	if err != nil {
+		return err
+	}
+	// Never overwrite an existing identity, documents may depend on it
+	if err = writeNewFile(p, data); err != nil {
+		return err
+	}
+	fmt.Println(publicKey)
+	return nil
+}
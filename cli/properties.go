@@ -0,0 +1,62 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: print the metadata of a document
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func runProperties(args []string) error {
+	fs := newFlagSet("properties")
+	unlock := addUnlockFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(assets.CliMissingArguments)
+	}
+	payload, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if _, err = unlock.unlock(payload); err != nil {
+		return err
+	}
+	printMetadata(crypto.DocumentMetadata())
+	return nil
+}
+
+func printMetadata(m crypto.Metadata) {
+	entry := func(name string, value string) {
+		if value != "" {
+			fmt.Printf(assets.CliPropertyEntry, name, value)
+		}
+	}
+	entry(assets.CapMetaTitle, m.Title)
+	entry(assets.CapMetaAuthor, m.Author)
+	entry(assets.CapMetaTags, strings.Join(m.Tags, ", "))
+	entry(assets.CapMetaCreated, formatTime(m.Created))
+	entry(assets.CapMetaModified, formatTime(m.Modified))
+	if p := crypto.DocumentPadding(); p != crypto.PaddingNone && p < len(assets.TxtPadding) {
+		entry(assets.CapPadding, assets.TxtPadding[p])
+	}
+	for _, p := range m.Properties {
+		fmt.Printf(assets.CliPropertyEntry, p.Key, p.Value)
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.DateTime)
+}
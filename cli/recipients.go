@@ -0,0 +1,109 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: list, add and remove the recipients of a document
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"SimpleTwofishEditor/filelock"
+	"errors"
+	"fmt"
+	"os"
+)
+
+func runRecipients(args []string) error {
+	sub, args, err := subCommand(args, "list", "add", "remove")
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("recipients " + sub)
+	unlock := addUnlockFlags(fs)
+	label := fs.String("l", "", assets.CliFlagLabel)
+	if err = fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 || sub != "list" && len(args) < 2 {
+		return errors.New(assets.CliMissingArguments)
+	}
+	p := args[0]
+	if sub != "list" {
+		// The document must not be saved by an editor while the key slots are rewritten
+		if err = lockDocument(p); err != nil {
+			return err
+		}
+		defer filelock.Release(p)
+	}
+	payload, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if sub == "list" {
+		return listKeySlots(payload)
+	}
+	if _, err = unlock.unlock(payload); err != nil {
+		return err
+	}
+	for _, publicKey := range args[1:] {
+		if sub == "add" {
+			err = crypto.AddRecipient(*label, publicKey)
+		} else {
+			err = removeRecipient(publicKey)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return rewriteKeySlots(p, payload)
+}
+
+func listKeySlots(payload []byte) error {
+	slots, message := crypto.PayloadKeySlots(payload)
+	if message != "" {
+		return errors.New(message)
+	}
+	for _, s := range slots {
+		if s.Recipient != "" {
+			fmt.Printf(assets.CliRecipientEntry, s.Index+1, s.Recipient, s.Label)
+		} else {
+			fmt.Printf(assets.CliSlotEntry, s.Index+1, assets.TxtFactors[s.Factors-1], s.Label)
+		}
+	}
+	return nil
+}
+
+func removeRecipient(publicKey string) error {
+	recipient, err := crypto.ParseRecipient(publicKey)
+	if err != nil {
+		return err
+	}
+	for _, s := range crypto.KeySlots() {
+		if s.Recipient == crypto.FormatRecipient(recipient) {
+			return crypto.RevokeKeySlot(s.Index)
+		}
+	}
+	return fmt.Errorf(assets.CliNoSuchRecipient, publicKey)
+}
+
+// A stale lock is removed, as the editor does when saving
+func lockDocument(p string) error {
+	if info, locked := filelock.LockedByOther(p); locked {
+		if !info.IsStale() {
+			return fmt.Errorf(assets.CliFileLocked, p, info.Describe())
+		}
+		filelock.Break(p)
+	}
+	return filelock.Acquire(p)
+}
+
+// The encrypted text is kept, only the header of the file changes
+func rewriteKeySlots(p string, payload []byte) error {
+	payload, message := crypto.RewriteKeySlots(payload)
+	if message != "" {
+		return errors.New(message)
+	}
+	return os.WriteFile(p, payload, 0644)
+}
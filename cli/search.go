@@ -0,0 +1,160 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: search the text of encrypted files, decrypted in memory only
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+type searchOptions struct {
+	re       *regexp.Regexp
+	context  int
+	password []byte
+	keyfile  []byte
+	failed   int
+}
+
+func runSearch(args []string) error {
+	fs := newFlagSet("search")
+	unlock := addUnlockFlags(fs)
+	regex := fs.Bool("e", false, assets.CliFlagRegex)
+	ignoreCase := fs.Bool("I", false, assets.CliFlagIgnoreCase)
+	context := fs.Int("C", 0, assets.CliFlagContext)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return errors.New(assets.CliMissingArguments)
+	}
+	re, err := crypto.SearchPattern(fs.Arg(0), *regex, *ignoreCase)
+	if err != nil {
+		return err
+	}
+	s := &searchOptions{re: re, context: max(*context, 0)}
+	defer s.clear()
+	if unlock.identity != "" {
+		if err = loadIdentity(unlock.identity); err != nil {
+			return err
+		}
+	}
+	if unlock.keyfile != "" {
+		if s.keyfile, err = os.ReadFile(unlock.keyfile); err != nil {
+			return err
+		}
+	}
+	for _, p := range fs.Args()[1:] {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			err = s.searchFolder(p)
+		} else {
+			s.searchFile(p, p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if s.failed > 0 {
+		return fmt.Errorf(assets.CliSearchFailed, s.failed)
+	}
+	return nil
+}
+
+func (s *searchOptions) clear() {
+	clear(s.password)
+	clear(s.keyfile)
+	crypto.CloseVault()
+}
+
+// The password is asked for once, when the first file needs it
+func (s *searchOptions) credentials(payload []byte) ([]byte, []byte, error) {
+	if crypto.UnlocksWithVault(payload) || crypto.UnlocksWithIdentity(payload) {
+		return nil, nil, nil
+	}
+	if s.keyfile != nil && crypto.RequiredFactors(payload) == crypto.FactorKeyfile {
+		return nil, s.keyfile, nil
+	}
+	if s.password == nil {
+		p, err := readPassword(assets.CliPromptPassword)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.password = p
+	}
+	return s.password, s.keyfile, nil
+}
+
+// Documents of a folder, the documents of a vault are opened with its master key and shown by name
+func (s *searchOptions) searchFolder(folder string) error {
+	if manifest, err := os.ReadFile(filepath.Join(folder, assets.VaultFileName)); err == nil {
+		password, keyfile, err := s.credentials(manifest)
+		if err != nil {
+			return err
+		}
+		crypto.PushCredentials(manifest, password, keyfile)
+		message := crypto.UnlockVault(manifest)
+		crypto.Invalidate()
+		if message != "" {
+			return fmt.Errorf("%s: %s", folder, message)
+		}
+		defer crypto.CloseVault()
+		for _, e := range crypto.VaultEntries() {
+			s.searchFile(filepath.Join(folder, crypto.VaultEntryFile(e.ID)), fmt.Sprintf(assets.CliVaultEntryName, e.Name, folder))
+		}
+		return nil
+	}
+	files, err := filepath.Glob(filepath.Join(folder, "*."+assets.FileExtension))
+	if err != nil {
+		return err
+	}
+	for _, p := range files {
+		s.searchFile(p, p)
+	}
+	return nil
+}
+
+// Files that cannot be searched are reported, the search goes on
+func (s *searchOptions) searchFile(p string, name string) {
+	report := func(message string) {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", name, message)
+		s.failed++
+	}
+	payload, err := os.ReadFile(p)
+	if err != nil {
+		report(err.Error())
+		return
+	}
+	password, keyfile, err := s.credentials(payload)
+	if err != nil {
+		report(err.Error())
+		return
+	}
+	matches, message := crypto.SearchPayload(payload, password, keyfile, s.re, s.context)
+	if message != "" {
+		report(message)
+		return
+	}
+	for i, m := range matches {
+		if s.context > 0 && i > 0 {
+			fmt.Println(assets.CliSearchSeparator)
+		}
+		for j, line := range m.Before {
+			fmt.Printf(assets.CliSearchContext, name, m.Line-len(m.Before)+j, line)
+		}
+		fmt.Printf(assets.CliSearchMatch, name, m.Line, m.Text)
+		for j, line := range m.After {
+			fmt.Printf(assets.CliSearchContext, name, m.Line+1+j, line)
+		}
+	}
+}
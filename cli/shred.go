@@ -0,0 +1,32 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: securely delete plain text files
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"fmt"
+	"os"
+)
+
+func runShred(args []string) error {
+	fs := newFlagSet("shred")
+	passes := fs.Int("n", crypto.ShredPasses, assets.CliFlagPasses)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New(assets.CliMissingArguments)
+	}
+	fmt.Fprintln(os.Stderr, assets.CliShredNotice)
+	for _, p := range fs.Args() {
+		if err := crypto.ShredFile(p, *passes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: read passwords from the terminal without echo, or a line from stdin if it is not a terminal
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func readPassword(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		p, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return p, err
+	}
+	line, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) && line == "" {
+		return nil, errors.New(assets.CliNoPassword)
+	}
+	if err != nil && line == "" {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// Ask twice when a new password is set
+func readNewPassword(prompt string, verify string) ([]byte, error) {
+	p, err := readPassword(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return p, nil
+	}
+	v, err := readPassword(verify)
+	if err != nil {
+		return nil, err
+	}
+	if string(p) != string(v) {
+		clear(p)
+		clear(v)
+		return nil, errors.New(assets.CliPasswordMismatch)
+	}
+	clear(v)
+	return p, nil
+}
@@ -0,0 +1,87 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Command line: open a document with an identity, or with password and/or keyfile
+//----------------------------------------------------------------------------------------------------------------------
+
+package cli
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"flag"
+	"os"
+)
+
+type unlockOptions struct {
+	identity string
+	keyfile  string
+}
+
+func addUnlockFlags(fs *flag.FlagSet) *unlockOptions {
+	u := &unlockOptions{}
+	fs.StringVar(&u.identity, "i", "", assets.CliFlagIdentity)
+	fs.StringVar(&u.keyfile, "k", "", assets.CliFlagKeyfile)
+	return u
+}
+
+// Decrypt the payload, this also opens the key slots of the document for changes
+func (u *unlockOptions) unlock(payload []byte) (string, error) {
+	if u.identity != "" {
+		if err := loadIdentity(u.identity); err != nil {
+			return "", err
+		}
+	}
+	if !crypto.UnlocksWithIdentity(payload) {
+		if err := u.pushFactors(crypto.RequiredFactors(payload)); err != nil {
+			return "", err
+		}
+	}
+	text, message := crypto.DecryptPayload(payload)
+	if message != "" {
+		return "", errors.New(message)
+	}
+	return text, nil
+}
+
+func (u *unlockOptions) pushFactors(required int) error {
+	var keyfile []byte
+	var err error
+	if u.keyfile != "" {
+		if keyfile, err = os.ReadFile(u.keyfile); err != nil {
+			return err
+		}
+		defer clear(keyfile)
+	}
+	if u.keyfile != "" && required == crypto.FactorKeyfile {
+		crypto.Push(nil)
+	} else {
+		p, err := readPassword(assets.CliPromptPassword)
+		if err != nil {
+			return err
+		}
+		crypto.Push(p)
+		clear(p)
+	}
+	if keyfile != nil {
+		crypto.PushKeyfile(keyfile)
+	}
+	return nil
+}
+
+func loadIdentity(p string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassword(assets.CliPromptPassphrase)
+	if err != nil {
+		return err
+	}
+	message := crypto.LoadIdentity(data, passphrase)
+	clear(passphrase)
+	if message != "" {
+		return errors.New(message)
+	}
+	return nil
+}
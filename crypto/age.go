@@ -0,0 +1,354 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// age file format v1 (https://age-encryption.org/v1) for import and export
+// Passphrase (scrypt) stanzas are written, scrypt and X25519 (identity loaded) stanzas are read
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	ageIntro        = "age-encryption.org/v1"
+	ageScryptLabel  = "age-encryption.org/v1/scrypt"
+	ageX25519Label  = "age-encryption.org/v1/X25519"
+	ageArmorBegin   = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageArmorEnd     = "-----END AGE ENCRYPTED FILE-----"
+	ageFileKeySize  = 16
+	ageNonceSize    = 16
+	ageChunkSize    = 64 * 1024
+	ageColumns      = 64
+	ageScryptLogN   = 18
+	ageScryptMaxLog = 22
+)
+
+var ageBase64 = base64.RawStdEncoding.Strict()
+
+type ageStanza struct {
+	kind string
+	args []string
+	body []byte
+}
+
+func isAgeFile(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(ageIntro+"\n")) || bytes.HasPrefix(bytes.TrimSpace(data), []byte(ageArmorBegin))
+}
+
+func ageHkdf(secret []byte, salt []byte, info string) []byte {
+	key := make([]byte, chacha20poly1305.KeySize)
+	_, _ = io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key)
+	return key
+}
+
+func ageSealKey(key []byte, fileKey []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, chacha20poly1305.NonceSize), fileKey, nil), nil
+}
+
+func ageOpenKey(key []byte, body []byte) ([]byte, bool) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil || len(body) != ageFileKeySize+chacha20poly1305.Overhead {
+		return nil, false
+	}
+	fileKey, err := aead.Open(nil, make([]byte, chacha20poly1305.NonceSize), body, nil)
+	return fileKey, err == nil
+}
+
+func ageScryptKey(passphrase []byte, salt []byte, logN int) ([]byte, error) {
+	return scrypt.Key(passphrase, append([]byte(ageScryptLabel), salt...), 1<<logN, 8, 1, chacha20poly1305.KeySize)
+}
+
+// Encrypt text as age file protected by a passphrase
+func encryptAge(payload []byte, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New(assets.ErrPassphraseRequired)
+	}
+	fileKey := make([]byte, ageFileKeySize)
+	salt := make([]byte, 16)
+	nonce := make([]byte, ageNonceSize)
+	for _, b := range [][]byte{fileKey, salt, nonce} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	key, err := ageScryptKey(passphrase, salt, ageScryptLogN)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ageSealKey(key, fileKey)
+	if err != nil {
+		return nil, err
+	}
+	stanza := ageStanza{kind: "scrypt", args: []string{ageBase64.EncodeToString(salt), strconv.Itoa(ageScryptLogN)}, body: body}
+	var header bytes.Buffer
+	header.WriteString(ageIntro + "\n")
+	stanza.writeTo(&header)
+	header.WriteString("---")
+	mac := hmac.New(sha256.New, ageHkdf(fileKey, nil, "header"))
+	mac.Write(header.Bytes())
+	header.WriteString(" " + ageBase64.EncodeToString(mac.Sum(nil)) + "\n")
+	header.Write(nonce)
+	stream, err := ageStream(ageHkdf(fileKey, nonce, "payload"), payload, true)
+	if err != nil {
+		return nil, err
+	}
+	return append(header.Bytes(), stream...), nil
+}
+
+func (s ageStanza) writeTo(b *bytes.Buffer) {
+	b.WriteString("-> " + s.kind)
+	for _, a := range s.args {
+		b.WriteString(" " + a)
+	}
+	b.WriteString("\n")
+	body := ageBase64.EncodeToString(s.body)
+	for {
+		n := min(len(body), ageColumns)
+		b.WriteString(body[:n] + "\n")
+		body = body[n:]
+		// A line shorter than 64 columns (maybe empty) ends the body
+		if n < ageColumns {
+			return
+		}
+	}
+}
+
+// STREAM: 64 KiB chunks, nonce is an 11 byte counter plus a flag for the last chunk
+func ageStream(key []byte, data []byte, seal bool) ([]byte, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	size := ageChunkSize
+	if !seal {
+		size += chacha20poly1305.Overhead
+	}
+	var outp []byte
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	for counter := uint64(0); ; counter++ {
+		n := min(len(data), size)
+		last := n == len(data)
+		binary.BigEndian.PutUint64(nonce[3:11], counter)
+		if last {
+			nonce[11] = 1
+		}
+		if seal {
+			outp = aead.Seal(outp, nonce, data[:n], nil)
+		} else {
+			if n == chacha20poly1305.Overhead && counter > 0 {
+				// Only an empty file has an empty last chunk
+				return nil, errors.New(assets.ErrCorrupted)
+			}
+			if outp, err = aead.Open(outp, nonce, data[:n], nil); err != nil {
+				return nil, errors.New(assets.ErrUnableToDecrypt)
+			}
+		}
+		data = data[n:]
+		if last {
+			return outp, nil
+		}
+	}
+}
+
+// Decrypt an age file with the passphrase or the identity loaded
+func decryptAge(data []byte, passphrase []byte) ([]byte, string) {
+	data, message := ageDearmor(data)
+	if message != "" {
+		return nil, message
+	}
+	stanzas, headerEnd, mac, rest, message := parseAgeHeader(data)
+	if message != "" {
+		return nil, message
+	}
+	var fileKey []byte
+	for _, s := range stanzas {
+		var ok bool
+		switch s.kind {
+		case "scrypt":
+			if len(stanzas) != 1 {
+				return nil, assets.ErrCorrupted
+			}
+			if len(passphrase) == 0 {
+				return nil, assets.ErrPassphraseRequired
+			}
+			fileKey, ok, message = ageUnwrapScrypt(s, passphrase)
+			if message != "" {
+				return nil, message
+			}
+		case "X25519":
+			fileKey, ok = ageUnwrapX25519(s)
+		}
+		if ok {
+			break
+		}
+	}
+	if fileKey == nil {
+		if len(passphrase) > 0 {
+			return nil, assets.ErrUnableToDecrypt
+		}
+		return nil, assets.ErrIdentityRequired
+	}
+	check := hmac.New(sha256.New, ageHkdf(fileKey, nil, "header"))
+	check.Write(data[:headerEnd])
+	if !hmac.Equal(check.Sum(nil), mac) {
+		return nil, assets.ErrCorrupted
+	}
+	if len(rest) < ageNonceSize+chacha20poly1305.Overhead {
+		return nil, assets.ErrCorrupted
+	}
+	payload, err := ageStream(ageHkdf(fileKey, rest[:ageNonceSize], "payload"), rest[ageNonceSize:], false)
+	if err != nil {
+		return nil, err.Error()
+	}
+	return payload, ""
+}
+
+func ageUnwrapScrypt(s ageStanza, passphrase []byte) ([]byte, bool, string) {
+	if len(s.args) != 2 {
+		return nil, false, assets.ErrCorrupted
+	}
+	salt, err := ageBase64.DecodeString(s.args[0])
+	logN, err2 := strconv.Atoi(s.args[1])
+	if err != nil || err2 != nil || len(salt) != 16 || logN <= 0 || strconv.Itoa(logN) != s.args[1] {
+		return nil, false, assets.ErrCorrupted
+	}
+	if logN > ageScryptMaxLog {
+		return nil, false, assets.ErrAgeWorkFactor
+	}
+	key, err := ageScryptKey(passphrase, salt, logN)
+	if err != nil {
+		return nil, false, assets.ErrCorrupted
+	}
+	fileKey, ok := ageOpenKey(key, s.body)
+	if !ok {
+		return nil, false, assets.ErrUnableToDecrypt
+	}
+	return fileKey, true, ""
+}
+
+func ageUnwrapX25519(s ageStanza) ([]byte, bool) {
+	identity := popIdentity()
+	if identity == nil || len(s.args) != 1 {
+		return nil, false
+	}
+	share, err := ageBase64.DecodeString(s.args[0])
+	if err != nil || len(share) != x25519KeySize {
+		return nil, false
+	}
+	pub, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, false
+	}
+	shared, err := identity.ECDH(pub)
+	if err != nil {
+		return nil, false
+	}
+	key := ageHkdf(shared, append(share, identityPublic[:]...), ageX25519Label)
+	clear(shared)
+	return ageOpenKey(key, s.body)
+}
+
+func ageUnlocksWithIdentity(data []byte) bool {
+	data, message := ageDearmor(data)
+	if message != "" {
+		return false
+	}
+	stanzas, _, _, _, message := parseAgeHeader(data)
+	if message != "" {
+		return false
+	}
+	for _, s := range stanzas {
+		if _, ok := ageUnwrapX25519(s); ok && s.kind == "X25519" {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns stanzas, length of the header covered by the MAC, the MAC and the rest of the file
+func parseAgeHeader(data []byte) ([]ageStanza, int, []byte, []byte, string) {
+	var stanzas []ageStanza
+	pos := 0
+	nextLine := func() (string, bool) {
+		i := bytes.IndexByte(data[pos:], '\n')
+		if i < 0 {
+			return "", false
+		}
+		line := string(data[pos : pos+i])
+		pos += i + 1
+		return line, true
+	}
+	if line, ok := nextLine(); !ok || line != ageIntro {
+		return nil, 0, nil, nil, assets.ErrCorrupted
+	}
+	for {
+		start := pos
+		line, ok := nextLine()
+		if !ok {
+			return nil, 0, nil, nil, assets.ErrCorrupted
+		}
+		if strings.HasPrefix(line, "--- ") {
+			mac, err := ageBase64.DecodeString(line[4:])
+			if err != nil || len(stanzas) == 0 {
+				return nil, 0, nil, nil, assets.ErrCorrupted
+			}
+			return stanzas, start + 3, mac, data[pos:], ""
+		}
+		fields := strings.Split(line, " ")
+		if len(fields) < 2 || fields[0] != "->" {
+			return nil, 0, nil, nil, assets.ErrCorrupted
+		}
+		s := ageStanza{kind: fields[1], args: fields[2:]}
+		for {
+			line, ok = nextLine()
+			if !ok || len(line) > ageColumns {
+				return nil, 0, nil, nil, assets.ErrCorrupted
+			}
+			b, err := ageBase64.DecodeString(line)
+			if err != nil {
+				return nil, 0, nil, nil, assets.ErrCorrupted
+			}
+			s.body = append(s.body, b...)
+			if len(line) < ageColumns {
+				break
+			}
+		}
+		stanzas = append(stanzas, s)
+	}
+}
+
+// ASCII armored files are decoded first
+func ageDearmor(data []byte) ([]byte, string) {
+	if bytes.HasPrefix(data, []byte(ageIntro+"\n")) {
+		return data, ""
+	}
+	text := strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+	if !strings.HasPrefix(text, ageArmorBegin) || !strings.HasSuffix(text, ageArmorEnd) {
+		return nil, assets.ErrCorrupted
+	}
+	text = strings.TrimSuffix(strings.TrimPrefix(text, ageArmorBegin), ageArmorEnd)
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(strings.TrimSpace(text), "\n", ""))
+	if err != nil {
+		return nil, assets.ErrCorrupted
+	}
+	return decoded, ""
+}
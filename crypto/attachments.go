@@ -0,0 +1,108 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Binary attachments of a document, one section of the encrypted body per attachment
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	attName byte = iota + 1
+	attData
+)
+
+type attachment struct {
+	name string
+	data []byte
+}
+
+type AttachmentInfo struct {
+	Index int
+	Name  string
+	Size  int
+}
+
+// Attachments of the open document
+var attachments []attachment
+
+func Attachments() []AttachmentInfo {
+	var result []AttachmentInfo
+	for i, a := range attachments {
+		result = append(result, AttachmentInfo{Index: i, Name: a.name, Size: len(a.data)})
+	}
+	return result
+}
+
+// Only the base name of a file is stored, names are unique within a document
+func AddAttachment(name string, data []byte) error {
+	name = AttachmentName(name)
+	if name == "" {
+		return errors.New(assets.ErrAttachmentName)
+	}
+	if slices.ContainsFunc(attachments, func(a attachment) bool { return a.name == name }) {
+		return errors.New(assets.ErrDuplicateAttachment)
+	}
+	attachments = append(attachments, attachment{name: name, data: slices.Clone(data)})
+	return nil
+}
+
+func AttachmentData(index int) ([]byte, error) {
+	if index < 0 || index >= len(attachments) {
+		return nil, errors.New(assets.ErrNoSuchAttachment)
+	}
+	return slices.Clone(attachments[index].data), nil
+}
+
+func RemoveAttachment(index int) error {
+	if index < 0 || index >= len(attachments) {
+		return errors.New(assets.ErrNoSuchAttachment)
+	}
+	clear(attachments[index].data)
+	attachments = slices.Delete(attachments, index, index+1)
+	return nil
+}
+
+// A name that is safe to be used as file name in any folder
+func AttachmentName(name string) string {
+	name = filepath.Base(filepath.Clean(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return ""
+	}
+	return name
+}
+
+func resetAttachments() {
+	for _, a := range attachments {
+		clear(a.data)
+	}
+	attachments = nil
+}
+
+func (a attachment) marshal() []byte {
+	b := appendRecord(nil, attName, []byte(a.name))
+	return appendRecord(b, attData, a.data)
+}
+
+func unmarshalAttachment(b []byte) (attachment, error) {
+	var a attachment
+	err := parseRecords(b, func(tag byte, value []byte) error {
+		switch tag {
+		case attName:
+			a.name = AttachmentName(string(value))
+		case attData:
+			a.data = slices.Clone(value)
+		}
+		return nil
+	})
+	if err == nil && a.name == "" {
+		err = errors.New(assets.ErrCorrupted)
+	}
+	return a, err
+}
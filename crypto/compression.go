@@ -0,0 +1,61 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Optional compression of the body before encryption, flagged in the container header
+// The size of a compressed file depends on the content, so it tells a little about the text
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+)
+
+const (
+	CompressionNone = iota
+	CompressionDeflate
+)
+
+// Used when a document is saved, files are read in whatever format the header tells
+var compression = CompressionNone
+
+func SetCompression(c int) {
+	compression = c
+}
+
+func compressBody(body []byte, c byte) ([]byte, error) {
+	if c == CompressionNone {
+		return body, nil
+	}
+	var b bytes.Buffer
+	w, err := flate.NewWriter(&b, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(body); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decompressBody(body []byte, c byte) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return body, nil
+	case CompressionDeflate:
+		r := flate.NewReader(bytes.NewReader(body))
+		defer r.Close()
+		inflated, err := io.ReadAll(r)
+		if err != nil {
+			return nil, errors.New(assets.ErrCorrupted)
+		}
+		return inflated, nil
+	}
+	return nil, errors.New(assets.ErrUnknownCompression)
+}
@@ -0,0 +1,416 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Container format v2: random content key wrapped in key slots, encrypt-then-MAC
+//
+// magic | header records (tag, uint32 length, value) ... tagEnd | Twofish-CBC(token | body, maybe compressed) | HMAC-SHA512
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"slices"
+)
+
+const (
+	tagEnd byte = iota
+	tagSlot
+	tagRecipient
+	tagCompression
+	tagVault
+)
+
+const (
+	kdfRounds    uint32 = 100000
+	maxKdfRounds        = 10 * kdfRounds // Rounds are read from the file, a huge count would block for hours
+	saltSize            = 32
+	slotCheckLen        = 16
+	recordHeader        = 5
+	slotSize            = 1 + 4 + saltSize + int(TwofishKeysize) + slotCheckLen
+)
+
+var dataPrefixV2 = append(append([]byte{}, dataPrefix...), []byte("V2!")...)
+
+type keySlot struct {
+	factors byte
+	rounds  uint32
+	salt    [saltSize]byte
+	wrapped [TwofishKeysize]byte
+	check   [slotCheckLen]byte
+	label   string
+}
+
+// The content key of the open document is kept (obscured) together with its key slots,
+// so saving does not require to derive the key from the password again
+type containerSession struct {
+	key        TfKey
+	slots      []keySlot
+	recipients []recipientStanza
+	compressed byte
+	vaultSlot  []byte
+	unlocked   int
+	rekey      bool
+	valid      bool
+}
+
+var session containerSession
+
+func closeSession() {
+	session = containerSession{}
+}
+
+func isContainerV2(data []byte) bool {
+	return len(data) >= len(dataPrefixV2) && slices.Equal(data[:len(dataPrefixV2)], dataPrefixV2)
+}
+
+func newSession() error {
+	var key TfKey
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	material := popKeyMaterial()
+	s, err := newKeySlot(key, byte(factors), material)
+	clear(material)
+	if err != nil {
+		return err
+	}
+	session = containerSession{key: encode(key), slots: []keySlot{s}, valid: true}
+	return nil
+}
+
+// Replace the slot used to open the document after the password has been changed
+func rekeySession() error {
+	if factors == 0 {
+		session.rekey = false
+		return nil
+	}
+	material := popKeyMaterial()
+	s, err := newKeySlot(decode(session.key), byte(factors), material)
+	clear(material)
+	if err != nil {
+		return err
+	}
+	if session.unlocked < 0 {
+		session.unlocked = len(session.slots)
+		session.slots = append(session.slots, s)
+	} else {
+		s.label = session.slots[session.unlocked].label
+		session.slots[session.unlocked] = s
+	}
+	session.rekey = false
+	return nil
+}
+
+func newKeySlot(key TfKey, f byte, material []byte) (keySlot, error) {
+	s := keySlot{factors: f, rounds: kdfRounds}
+	if _, err := rand.Read(s.salt[:]); err != nil {
+		return s, err
+	}
+	wrapKey, checkKey := deriveSlotKeys(s, material)
+	s.wrapped, s.check = wrapContentKey(key, wrapKey, checkKey)
+	return s, nil
+}
+
+func wrapContentKey(key TfKey, wrapKey TfKey, checkKey TfKey) ([TwofishKeysize]byte, [slotCheckLen]byte) {
+	var wrapped [TwofishKeysize]byte
+	var check [slotCheckLen]byte
+	tf := NewTwofish(wrapKey)
+	copy(wrapped[:], key[:])
+	for i := uint32(0); i < TwofishKeysize; i += TwofishBlocksize {
+		tf.EncryptBlock((*TfBlock)(wrapped[i : i+TwofishBlocksize]))
+	}
+	mac := HmacSha512(checkKey[:], wrapped[:])
+	copy(check[:], mac[:])
+	return wrapped, check
+}
+
+func unwrapContentKey(wrapped [TwofishKeysize]byte, check [slotCheckLen]byte, wrapKey TfKey, checkKey TfKey) (TfKey, bool) {
+	var key TfKey
+	mac := HmacSha512(checkKey[:], wrapped[:])
+	if !hmac.Equal(mac[:slotCheckLen], check[:]) {
+		return key, false
+	}
+	tf := NewTwofish(wrapKey)
+	copy(key[:], wrapped[:])
+	for i := uint32(0); i < TwofishKeysize; i += TwofishBlocksize {
+		tf.DecryptBlock((*TfBlock)(key[i : i+TwofishBlocksize]))
+	}
+	return key, true
+}
+
+func deriveSlotKeys(s keySlot, material []byte) (TfKey, TfKey) {
+	var wrapKey, checkKey TfKey
+	kek := Pbkdf2Sha512(material, s.salt[:], s.rounds, 2*int(TwofishKeysize))
+	copy(wrapKey[:], kek[:TwofishKeysize])
+	copy(checkKey[:], kek[TwofishKeysize:])
+	clear(kek)
+	return wrapKey, checkKey
+}
+
+// Returns the content key if the factors in the enclave open the slot
+func (s keySlot) unwrap() (TfKey, bool) {
+	var key TfKey
+	if int(s.factors) != factors {
+		return key, false
+	}
+	material := popKeyMaterial()
+	wrapKey, checkKey := deriveSlotKeys(s, material)
+	clear(material)
+	return unwrapContentKey(s.wrapped, s.check, wrapKey, checkKey)
+}
+
+func (s keySlot) marshal() []byte {
+	b := make([]byte, 0, slotSize+len(s.label))
+	b = append(b, s.factors)
+	b = binary.BigEndian.AppendUint32(b, s.rounds)
+	b = append(b, s.salt[:]...)
+	b = append(b, s.wrapped[:]...)
+	b = append(b, s.check[:]...)
+	return append(b, s.label...)
+}
+
+func unmarshalKeySlot(b []byte) (keySlot, bool) {
+	var s keySlot
+	if len(b) < slotSize {
+		return s, false
+	}
+	s.label = string(b[slotSize:])
+	s.factors = b[0]
+	s.rounds = binary.BigEndian.Uint32(b[1:5])
+	b = b[5:]
+	copy(s.salt[:], b[:saltSize])
+	b = b[saltSize:]
+	copy(s.wrapped[:], b[:TwofishKeysize])
+	copy(s.check[:], b[TwofishKeysize:TwofishKeysize+slotCheckLen])
+	// A slot needs a password, a keyfile or both
+	valid := s.factors >= FactorPassword && s.factors <= FactorPassword|FactorKeyfile
+	return s, valid && s.rounds > 0 && s.rounds <= maxKdfRounds
+}
+
+func appendRecord(b []byte, tag byte, value []byte) []byte {
+	b = append(b, tag)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...)
+}
+
+// Call fn for the records of a section, the section ends with the data
+func parseRecords(b []byte, fn func(tag byte, value []byte) error) error {
+	for len(b) > 0 {
+		if len(b) < recordHeader {
+			return errors.New(assets.ErrCorrupted)
+		}
+		tag := b[0]
+		l := binary.BigEndian.Uint32(b[1:recordHeader])
+		b = b[recordHeader:]
+		if uint64(l) > uint64(len(b)) {
+			return errors.New(assets.ErrCorrupted)
+		}
+		if err := fn(tag, b[:l]); err != nil {
+			return err
+		}
+		b = b[l:]
+	}
+	return nil
+}
+
+type containerHeader struct {
+	slots      []keySlot
+	recipients []recipientStanza
+	compressed byte
+	vaultSlot  []byte
+}
+
+// Split the container into header records and encrypted part, unknown records are ignored
+func parseHeader(data []byte) (containerHeader, []byte, error) {
+	var header containerHeader
+	data = data[len(dataPrefixV2):]
+	for {
+		if len(data) < recordHeader {
+			return header, nil, errors.New(assets.ErrCorrupted)
+		}
+		tag := data[0]
+		l := binary.BigEndian.Uint32(data[1:recordHeader])
+		data = data[recordHeader:]
+		if uint64(l) > uint64(len(data)) {
+			return header, nil, errors.New(assets.ErrCorrupted)
+		}
+		value := data[:l]
+		data = data[l:]
+		switch tag {
+		case tagEnd:
+			return header, data, nil
+		case tagSlot:
+			s, ok := unmarshalKeySlot(value)
+			if !ok {
+				return header, nil, errors.New(assets.ErrCorrupted)
+			}
+			header.slots = append(header.slots, s)
+		case tagRecipient:
+			r, ok := unmarshalRecipient(value)
+			if !ok {
+				return header, nil, errors.New(assets.ErrCorrupted)
+			}
+			header.recipients = append(header.recipients, r)
+		case tagCompression:
+			if len(value) != 1 {
+				return header, nil, errors.New(assets.ErrCorrupted)
+			}
+			header.compressed = value[0]
+		case tagVault:
+			header.vaultSlot = value
+		}
+	}
+}
+
+func contentKeys(key TfKey) (TfKey, ShaResult) {
+	var encKey TfKey
+	enc := HmacSha512(key[:], []byte("enc"))
+	copy(encKey[:], enc[:TwofishKeysize])
+	return encKey, HmacSha512(key[:], []byte("mac"))
+}
+
+func encryptContainerV2(body []byte) ([]byte, error) {
+	if !session.valid {
+		if err := newSession(); err != nil {
+			return nil, err
+		}
+	} else if session.rekey {
+		if err := rekeySession(); err != nil {
+			return nil, err
+		}
+	}
+	session.compressed = byte(compression)
+	body, err := compressBody(body, session.compressed)
+	if err != nil {
+		return nil, err
+	}
+	outp := marshalHeader()
+	pad := paddingSize(tokenSize+len(body), padding)
+	token := make([]byte, tokenSize, tokenSize+len(body)+pad)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	key := decode(session.key)
+	encKey, macKey := contentKeys(key)
+	tf := NewTwofish(encKey)
+	outp = append(outp, tf.CbcEncrypt(append(append(token, body...), make([]byte, pad)...))...)
+	mac := HmacSha512(macKey[:], outp)
+	return append(outp, mac[:]...), nil
+}
+
+func marshalHeader() []byte {
+	outp := make([]byte, len(dataPrefixV2))
+	copy(outp, dataPrefixV2)
+	for _, s := range session.slots {
+		outp = appendRecord(outp, tagSlot, s.marshal())
+	}
+	for _, r := range session.recipients {
+		outp = appendRecord(outp, tagRecipient, r.marshal())
+	}
+	if session.vaultSlot != nil {
+		outp = appendRecord(outp, tagVault, session.vaultSlot)
+	}
+	if session.compressed != CompressionNone {
+		outp = appendRecord(outp, tagCompression, []byte{session.compressed})
+	}
+	return appendRecord(outp, tagEnd, nil)
+}
+
+func decryptContainerV2(data []byte) ([]byte, string) {
+	if len(data) < len(dataPrefixV2)+recordHeader+Sha512Shabytes {
+		return nil, assets.ErrCorrupted
+	}
+	header, rest, err := parseHeader(data)
+	if err != nil || len(rest) < Sha512Shabytes+int(TwofishBlocksize) {
+		return nil, assets.ErrCorrupted
+	}
+	// The vault and an identity are tried first, they do not need a password
+	if key, ok := unwrapWithVault(header.vaultSlot); ok {
+		return openContainer(data, rest, key, header, -1)
+	}
+	if key, ok := unwrapWithIdentity(header.recipients); ok {
+		return openContainer(data, rest, key, header, -1)
+	}
+	if len(header.slots) == 0 && len(header.recipients) > 0 {
+		return nil, assets.ErrIdentityRequired
+	}
+	if len(header.slots) == 0 && header.vaultSlot != nil {
+		return nil, assets.ErrVaultRequired
+	}
+	if message := checkFactors(header.slots); message != "" {
+		return nil, message
+	}
+	for i, s := range header.slots {
+		if key, ok := s.unwrap(); ok {
+			return openContainer(data, rest, key, header, i)
+		}
+	}
+	return nil, assets.ErrUnableToDecrypt
+}
+
+func openContainer(data []byte, rest []byte, key TfKey, header containerHeader, unlocked int) ([]byte, string) {
+	encKey, macKey := contentKeys(key)
+	macPos := len(data) - Sha512Shabytes
+	mac := HmacSha512(macKey[:], data[:macPos])
+	if !hmac.Equal(mac[:], data[macPos:]) {
+		return nil, assets.ErrCorrupted
+	}
+	tf := NewTwofish(encKey)
+	tmp := tf.CbcDecrypt(rest[:len(rest)-Sha512Shabytes])
+	if len(tmp) < tokenSize {
+		return nil, assets.ErrCorrupted
+	}
+	body, err := decompressBody(tmp[tokenSize:], header.compressed)
+	if err != nil {
+		return nil, err.Error()
+	}
+	session = containerSession{key: encode(key), slots: header.slots, recipients: header.recipients,
+		compressed: header.compressed, vaultSlot: header.vaultSlot, unlocked: unlocked, valid: true}
+	return body, ""
+}
+
+// Tell the user which factor is missing if no slot accepts the factors entered
+func checkFactors(slots []keySlot) string {
+	required := -1
+	for _, s := range slots {
+		if int(s.factors) == factors {
+			return ""
+		}
+		if required < 0 || int(s.factors) < required {
+			required = int(s.factors)
+		}
+	}
+	switch {
+	case required < 0:
+		return assets.ErrCorrupted
+	case required&FactorKeyfile != 0 && factors&FactorKeyfile == 0:
+		return assets.ErrKeyfileRequired
+	case required&FactorPassword != 0 && factors&FactorPassword == 0:
+		return assets.ErrPasswordRequired
+	}
+	return assets.ErrNoKeyfileRequired
+}
+
+// Factors required by the key slots of a container, 0 if unknown
+func RequiredFactors(payload []byte) int {
+	if !isContainerV2(payload) {
+		if len(payload) >= len(dataPrefix) {
+			return FactorPassword
+		}
+		return 0
+	}
+	header, _, err := parseHeader(payload)
+	if err != nil || len(header.slots) == 0 {
+		return 0
+	}
+	slots := header.slots
+	required := int(slots[0].factors)
+	for _, s := range slots[1:] {
+		required &= int(s.factors)
+	}
+	return required
+}
@@ -1,6 +1,7 @@
 //----------------------------------------------------------------------------------------------------------------------
 // (w) 2024 by Jan Buchholz
 // Create final encryption/decryption key from password entered
+// Keep password and keyfile hash (v2 containers) in obscured form
 //----------------------------------------------------------------------------------------------------------------------
 
 package crypto
@@ -8,7 +9,15 @@ package crypto
 const shaKeyRounds = 1234
 const bits = 8
 
+const (
+	FactorPassword = 1 << iota
+	FactorKeyfile
+)
+
 var vault [TwofishKeysize]byte
+var pwdVault []byte
+var keyfileVault ShaResult
+var factors int
 var valid bool
 
 func init() {
@@ -28,15 +37,86 @@ func Push(p []byte) {
 		tmp[l] = buffer[l] + buffer[l+TwofishKeysize]
 	}
 	vault = encode(tmp)
+	clear(pwdVault)
+	pwdVault = rotate(p, ror)
+	keyfileVault = ShaResult{}
+	factors = 0
+	if len(p) > 0 {
+		factors = FactorPassword
+	}
+	session.rekey = true
+	Validate()
+}
+
+// Add a keyfile as second (or only) factor, call after Push
+func PushKeyfile(k []byte) {
+	sha := NewSha512()
+	hash := sha.Compute(k)
+	copy(keyfileVault[:], rotate(hash[:], ror))
+	factors |= FactorKeyfile
+	session.rekey = true
 	Validate()
 }
 
+// Push the password and/or keyfile given for a file, the password is left out if the keyfile alone opens it.
+// Nothing is pushed if both are nil
+func PushCredentials(payload []byte, password []byte, keyfile []byte) {
+	if password == nil && keyfile == nil {
+		return
+	}
+	if keyfile != nil && RequiredFactors(payload) == FactorKeyfile {
+		Push(nil)
+	} else {
+		Push(password)
+	}
+	if keyfile != nil {
+		PushKeyfile(keyfile)
+	}
+}
+
 func Pop() TfKey {
 	return decode(vault)
 }
 
+// Factors pushed into the enclave
+func Factors() int {
+	return factors
+}
+
+// Password and keyfile hash in the enclave combined, input for the key derivation of v2 containers
+func popKeyMaterial() []byte {
+	p := rotate(pwdVault, rol)
+	k := rotate(keyfileVault[:], rol)
+	material := keyMaterial(factors, p, k)
+	clear(p)
+	clear(k)
+	return material
+}
+
+func keyMaterial(f int, password []byte, keyfileHash []byte) []byte {
+	material := []byte{byte(f)}
+	if f&FactorPassword != 0 {
+		l := len(password)
+		material = append(material, byte(l>>24), byte(l>>16), byte(l>>8), byte(l))
+		material = append(material, password...)
+	}
+	if f&FactorKeyfile != 0 {
+		material = append(material, keyfileHash...)
+	}
+	return material
+}
+
 func Invalidate() {
 	vault = [TwofishKeysize]byte{}
+	clear(pwdVault)
+	pwdVault = nil
+	keyfileVault = ShaResult{}
+	factors = 0
+	closeSession()
+	resetMetadata()
+	resetAttachments()
+	resetRecords()
+	padding = PaddingNone
 	valid = false
 }
 
@@ -50,29 +130,26 @@ func IsValid() bool {
 
 func encode(b TfKey) TfKey {
 	var c TfKey
-	copy(c[:], b[:])
-	j := 0
-	for i, e := range c {
-		i++
-		if i > bits-1 {
-			i = 1
-		}
-		c[j] = ror(e, i)
-		j++
-	}
+	copy(c[:], rotate(b[:], ror))
 	return c
 }
 
 func decode(b TfKey) TfKey {
 	var c TfKey
-	copy(c[:], b[:])
+	copy(c[:], rotate(b[:], rol))
+	return c
+}
+
+func rotate(b []byte, f func(byte, int) byte) []byte {
+	c := make([]byte, len(b))
+	copy(c, b)
 	j := 0
-	for i, d := range c {
+	for i, e := range c {
 		i++
 		if i > bits-1 {
 			i = 1
 		}
-		c[j] = rol(d, i)
+		c[j] = f(e, i)
 		j++
 	}
 	return c
@@ -7,68 +7,222 @@ package crypto
 
 import (
 	"SimpleTwofishEditor/assets"
-	"crypto/rand"
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"slices"
 )
 
 const tokenSize = 16
 
+// Files are written in this format
+const ContainerVersionLatest = 2
+
+const (
+	sectionEnd byte = iota
+	sectionText
+	sectionMetadata
+	sectionAttachment
+	sectionPadding
+	sectionVaultEntry
+	sectionRecord
+)
+
+const (
+	FormatAge = iota + 1
+	FormatOpenPGP
+	FormatOpenPGPTwofish
+)
+
 var dataPrefix = []byte("!SiMpLe!TwOfIsH!EdItOr!")
 
+// Files are always written in container format v2, together with the metadata, attachments and records of the document
 func EncryptPayload(payload []byte) ([]byte, error) {
-	var outp = make([]byte, len(dataPrefix))
-	copy(outp, dataPrefix)
-	if len(payload) > 0 {
-		data := make([]byte, len(payload))
-		copy(data, payload)
-		token := make([]byte, tokenSize)
-		if _, err := rand.Read(token); err != nil {
-			return nil, err
-		}
-		stage0 := make([]byte, tokenSize)
-		copy(stage0, token)
-		stage0 = append(stage0, data...)
-		sha := NewSha512()
-		shaResult := sha.Compute(stage0)
-		outp = append(outp, shaResult[:]...)
-		tf := NewTwofishWithEnclave()
-		stage1 := tf.CbcEncrypt(stage0)
-		outp = append(outp, stage1...)
-	}
-	return outp, nil
+	touchMetadata()
+	body := appendRecord(nil, sectionText, payload)
+	body = appendRecord(body, sectionMetadata, metadata.marshal())
+	for _, a := range attachments {
+		body = appendRecord(body, sectionAttachment, a.marshal())
+	}
+	for _, r := range records {
+		body = appendRecord(body, sectionRecord, r.marshal())
+	}
+	if padding != PaddingNone {
+		body = appendRecord(body, sectionPadding, []byte{byte(padding)})
+	}
+	body = appendRecord(body, sectionEnd, nil)
+	return encryptContainerV2(body)
 }
 
 func DecryptPayload(payload []byte) (string, string) {
 	if len(payload) > 0 {
 		data := make([]byte, len(payload))
 		copy(data, payload)
-		if len(data) < len(dataPrefix) {
-			return "", assets.ErrNoMatch
-		}
-		for i := 0; i < len(dataPrefix); i++ {
-			if dataPrefix[i] != data[i] {
-				return "", assets.ErrNoMatch
+		if isContainerV2(data) {
+			body, message := decryptContainerV2(data)
+			if message != "" {
+				return "", message
 			}
+			return parseBody(body)
 		}
-		if len(data) == len(dataPrefix) {
-			return "", "" //empty Zydeco file
+		return decryptPayloadV1(data)
+	}
+	return "", assets.ErrEmptyFile
+}
+
+// Container format of a file, 0 if it is no file of this editor
+func ContainerVersion(payload []byte) int {
+	switch {
+	case isContainerV2(payload):
+		return ContainerVersionLatest
+	case bytes.HasPrefix(payload, dataPrefix):
+		return 1
+	}
+	return 0
+}
+
+// Write text in a foreign format for exchange with other tools, protected by the passphrase given
+func ExportPayload(payload []byte, format int, passphrase []byte) ([]byte, error) {
+	switch format {
+	case FormatAge:
+		return encryptAge(payload, passphrase)
+	case FormatOpenPGP:
+		return encryptOpenPGP(payload, passphrase, pgpCipherAES256)
+	case FormatOpenPGPTwofish:
+		return encryptOpenPGP(payload, passphrase, pgpCipherTwofish)
+	}
+	return nil, errors.New(assets.ErrUnknownFormat)
+}
+
+// Read text from a foreign format, the passphrase may be nil if the identity loaded opens the file
+func ImportPayload(payload []byte, passphrase []byte) (string, string) {
+	var text []byte
+	var message string
+	switch ImportFormat(payload) {
+	case FormatAge:
+		text, message = decryptAge(payload, passphrase)
+	case FormatOpenPGP:
+		text, message = decryptOpenPGP(payload, passphrase)
+	default:
+		return "", assets.ErrUnknownFormat
+	}
+	if message != "" {
+		return "", message
+	}
+	return string(text), ""
+}
+
+func ImportFormat(payload []byte) int {
+	if isAgeFile(payload) {
+		return FormatAge
+	}
+	if isOpenPGPFile(payload) {
+		return FormatOpenPGP
+	}
+	return 0
+}
+
+// True if the file can be imported without passphrase
+func ImportUnlocksWithIdentity(payload []byte) bool {
+	if ImportFormat(payload) != FormatAge || !IdentityLoaded() {
+		return false
+	}
+	return ageUnlocksWithIdentity(payload)
+}
+
+func parseBody(body []byte) (string, string) {
+	var text []byte
+	var m Metadata
+	var files []attachment
+	var recs []Record
+	var err error
+	pad := PaddingNone
+	for {
+		if len(body) < recordHeader {
+			return "", assets.ErrCorrupted
 		}
-		if len(data) < tokenSize+len(dataPrefix)+Sha512Shabytes+1 {
+		tag := body[0]
+		l := binary.BigEndian.Uint32(body[1:recordHeader])
+		body = body[recordHeader:]
+		if uint64(l) > uint64(len(body)) {
 			return "", assets.ErrCorrupted
 		}
-		data = data[len(dataPrefix):]
-		shaCheck := data[:Sha512Shabytes]
-		data = data[Sha512Shabytes:]
-		tf := NewTwofishWithEnclave()
-		tmp := tf.CbcDecrypt(data)
-		sha := NewSha512()
-		shaResult := sha.Compute(tmp)
-		tmp = tmp[tokenSize:]
-		r := slices.Equal(shaCheck[:], shaResult[:])
-		if !r {
-			return "", assets.ErrUnableToDecrypt
+		value := body[:l]
+		body = body[l:]
+		switch tag {
+		case sectionEnd:
+			metadata = m
+			resetAttachments()
+			attachments = files
+			records = recs
+			padding = pad
+			return string(text), ""
+		case sectionText:
+			text = value
+		case sectionMetadata:
+			if m, err = unmarshalMetadata(value); err != nil {
+				return "", err.Error()
+			}
+		case sectionAttachment:
+			a, err := unmarshalAttachment(value)
+			if err != nil {
+				return "", err.Error()
+			}
+			files = append(files, a)
+		case sectionRecord:
+			r, err := unmarshalRecord(value)
+			if err != nil {
+				return "", err.Error()
+			}
+			recs = append(recs, r)
+		case sectionPadding:
+			if len(value) != 1 {
+				return "", assets.ErrCorrupted
+			}
+			pad = int(value[0])
 		}
-		return string(tmp), ""
 	}
-	return "", assets.ErrEmptyFile
+}
+
+func decryptPayloadV1(data []byte) (string, string) {
+	if len(data) < len(dataPrefix) {
+		return "", assets.ErrNoMatch
+	}
+	for i := 0; i < len(dataPrefix); i++ {
+		if dataPrefix[i] != data[i] {
+			return "", assets.ErrNoMatch
+		}
+	}
+	if len(data) == len(dataPrefix) {
+		closeSession()
+		resetMetadata()
+		resetAttachments()
+		resetRecords()
+		padding = PaddingNone
+		return "", "" //empty Zydeco file
+	}
+	if len(data) < tokenSize+len(dataPrefix)+Sha512Shabytes+1 {
+		return "", assets.ErrCorrupted
+	}
+	if factors != FactorPassword {
+		return "", assets.ErrPasswordOnly
+	}
+	data = data[len(dataPrefix):]
+	shaCheck := data[:Sha512Shabytes]
+	data = data[Sha512Shabytes:]
+	tf := NewTwofishWithEnclave()
+	tmp := tf.CbcDecrypt(data)
+	sha := NewSha512()
+	shaResult := sha.Compute(tmp)
+	tmp = tmp[tokenSize:]
+	r := slices.Equal(shaCheck[:], shaResult[:])
+	if !r {
+		return "", assets.ErrUnableToDecrypt
+	}
+	closeSession()
+	resetMetadata()
+	resetAttachments()
+	resetRecords()
+	padding = PaddingNone
+	return string(tmp), ""
 }
@@ -0,0 +1,94 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Generate random passwords and diceware passphrases using crypto/rand
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/rand"
+	"errors"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+const (
+	AlphabetLower   = "abcdefghijklmnopqrstuvwxyz"
+	AlphabetUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	AlphabetDigits  = "0123456789"
+	AlphabetSymbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+var dicewareWords []string
+
+func GeneratePassword(length int, alphabet string) (string, error) {
+	runes := uniqueRunes(alphabet)
+	if length < 1 || len(runes) < 2 {
+		return "", errors.New(assets.ErrGeneratorParameters)
+	}
+	result := make([]rune, length)
+	for i := range result {
+		n, err := randomIndex(len(runes))
+		if err != nil {
+			return "", err
+		}
+		result[i] = runes[n]
+	}
+	return string(result), nil
+}
+
+func GeneratePassphrase(words int, separator string) (string, error) {
+	list := dicewareWordList()
+	if words < 1 || len(list) == 0 {
+		return "", errors.New(assets.ErrGeneratorParameters)
+	}
+	result := make([]string, words)
+	for i := range result {
+		n, err := randomIndex(len(list))
+		if err != nil {
+			return "", err
+		}
+		result[i] = list[n]
+	}
+	return strings.Join(result, separator), nil
+}
+
+// Entropy in bits of a generated password
+func PasswordEntropy(length int, alphabet string) float64 {
+	return float64(length) * math.Log2(float64(max(len(uniqueRunes(alphabet)), 1)))
+}
+
+// Entropy in bits of a generated passphrase
+func PassphraseEntropy(words int) float64 {
+	return float64(words) * math.Log2(float64(max(len(dicewareWordList()), 1)))
+}
+
+// Uniformly distributed random number in [0, n)
+func randomIndex(n int) (int, error) {
+	r, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(r.Int64()), nil
+}
+
+func uniqueRunes(s string) []rune {
+	runes := []rune(s)
+	slices.Sort(runes)
+	return slices.Compact(runes)
+}
+
+// EFF large word list, lines consist of dice rolls and word
+func dicewareWordList() []string {
+	if dicewareWords == nil {
+		for _, line := range strings.Split(assets.DicewareWords, "\n") {
+			if fields := strings.Fields(line); len(fields) == 2 {
+				dicewareWords = append(dicewareWords, fields[1])
+			}
+		}
+	}
+	return dicewareWords
+}
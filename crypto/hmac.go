@@ -0,0 +1,53 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// HMAC-SHA512 (RFC 2104) and PBKDF2-HMAC-SHA512 (RFC 8018) based on our Sha512
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+const hmacBlockSize = int(shaBlockSize)
+const hmacIpad byte = 0x36
+const hmacOpad byte = 0x5c
+
+func HmacSha512(key []byte, message []byte) ShaResult {
+	sha := NewSha512()
+	k := make([]byte, hmacBlockSize)
+	if len(key) > hmacBlockSize {
+		hash := sha.Compute(key)
+		copy(k, hash[:])
+	} else {
+		copy(k, key)
+	}
+	inner := make([]byte, hmacBlockSize, hmacBlockSize+len(message))
+	outer := make([]byte, hmacBlockSize, hmacBlockSize+Sha512Shabytes)
+	for i := 0; i < hmacBlockSize; i++ {
+		inner[i] = k[i] ^ hmacIpad
+		outer[i] = k[i] ^ hmacOpad
+	}
+	inner = append(inner, message...)
+	hash := sha.Compute(inner)
+	outer = append(outer, hash[:]...)
+	clear(k)
+	clear(inner)
+	return sha.Compute(outer)
+}
+
+func Pbkdf2Sha512(password []byte, salt []byte, rounds uint32, keyLen int) []byte {
+	var block uint32
+	var result []byte
+	for block = 1; len(result) < keyLen; block++ {
+		msg := make([]byte, len(salt), len(salt)+4)
+		copy(msg, salt)
+		msg = append(msg, byte(block>>24), byte(block>>16), byte(block>>8), byte(block))
+		u := HmacSha512(password, msg)
+		t := u
+		for i := uint32(1); i < rounds; i++ {
+			u = HmacSha512(password, u[:])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		result = append(result, t[:]...)
+	}
+	return result[:keyLen]
+}
@@ -0,0 +1,104 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Identity file: X25519 private key protected by a passphrase
+//
+// magic | rounds | salt | Twofish-CBC(token | AGE-SECRET-KEY-1...) | HMAC-SHA512
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+)
+
+var identityPrefix = []byte("!SiMpLe!TwOfIsH!IdEnTiTy!")
+
+func identityKeys(passphrase []byte, salt []byte, rounds uint32) (TfKey, []byte) {
+	var encKey TfKey
+	kek := Pbkdf2Sha512(passphrase, salt, rounds, 2*int(TwofishKeysize))
+	copy(encKey[:], kek[:TwofishKeysize])
+	return encKey, kek[TwofishKeysize:]
+}
+
+// Encrypt the identity loaded for storing it in a file
+func EncryptIdentity(passphrase []byte) ([]byte, error) {
+	identity := popIdentity()
+	if identity == nil {
+		return nil, errors.New(assets.ErrNoIdentity)
+	}
+	secret, err := bech32Encode(identityHrp, identity.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	outp := make([]byte, len(identityPrefix))
+	copy(outp, identityPrefix)
+	outp = binary.BigEndian.AppendUint32(outp, kdfRounds)
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, err
+	}
+	outp = append(outp, salt...)
+	token := make([]byte, tokenSize, tokenSize+len(secret))
+	if _, err = rand.Read(token); err != nil {
+		return nil, err
+	}
+	encKey, macKey := identityKeys(passphrase, salt, kdfRounds)
+	tf := NewTwofish(encKey)
+	outp = append(outp, tf.CbcEncrypt(append(token, secret...))...)
+	mac := HmacSha512(macKey, outp)
+	clear(macKey)
+	return append(outp, mac[:]...), nil
+}
+
+// Decrypt an identity file and keep the identity in memory, returns an error message on failure
+func LoadIdentity(data []byte, passphrase []byte) string {
+	if len(data) < len(identityPrefix)+4+saltSize+int(TwofishBlocksize)+Sha512Shabytes ||
+		!slices.Equal(data[:len(identityPrefix)], identityPrefix) {
+		return assets.ErrNoIdentityFile
+	}
+	rest := data[len(identityPrefix):]
+	rounds := binary.BigEndian.Uint32(rest[:4])
+	salt := rest[4 : 4+saltSize]
+	if rounds == 0 || rounds > maxKdfRounds {
+		return assets.ErrCorrupted
+	}
+	encKey, macKey := identityKeys(passphrase, salt, rounds)
+	macPos := len(data) - Sha512Shabytes
+	mac := HmacSha512(macKey, data[:macPos])
+	clear(macKey)
+	if !hmac.Equal(mac[:], data[macPos:]) {
+		return assets.ErrUnableToDecrypt
+	}
+	tf := NewTwofish(encKey)
+	tmp := tf.CbcDecrypt(rest[4+saltSize : len(rest)-Sha512Shabytes])
+	if len(tmp) < tokenSize {
+		return assets.ErrCorrupted
+	}
+	identity, message := parseIdentity(string(tmp[tokenSize:]))
+	clear(tmp)
+	if identity == nil {
+		return message
+	}
+	pushIdentity(identity)
+	return ""
+}
+
+func parseIdentity(s string) (*ecdh.PrivateKey, string) {
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil || hrp != strings.ToLower(identityHrp) || len(data) != x25519KeySize {
+		return nil, assets.ErrCorrupted
+	}
+	identity, err := ecdh.X25519().NewPrivateKey(data)
+	clear(data)
+	if err != nil {
+		return nil, assets.ErrCorrupted
+	}
+	return identity, ""
+}
@@ -0,0 +1,157 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Manage the key slots of the open document: list, add and revoke passwords and recipients
+// The content key stays the same, so the body does not need to be encrypted again
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/hmac"
+	"errors"
+	"slices"
+)
+
+// Recipients follow the password slots, they have no factors but a public key
+type KeySlotInfo struct {
+	Index     int
+	Label     string
+	Factors   int
+	Recipient string
+	Unlocked  bool
+}
+
+func KeySlots() []KeySlotInfo {
+	if !session.valid {
+		if !valid {
+			return nil
+		}
+		return []KeySlotInfo{{Index: 0, Factors: factors, Unlocked: true}}
+	}
+	header := containerHeader{slots: session.slots, recipients: session.recipients}
+	return slotInfos(header, session.unlocked, session.unlocked < 0 && IdentityLoaded())
+}
+
+// Slots and recipients of a container as stored in the header, no password required
+func PayloadKeySlots(payload []byte) ([]KeySlotInfo, string) {
+	if !isContainerV2(payload) {
+		return nil, assets.ErrNoContainerV2
+	}
+	header, _, err := parseHeader(payload)
+	if err != nil {
+		return nil, assets.ErrCorrupted
+	}
+	return slotInfos(header, -1, false), ""
+}
+
+func slotInfos(header containerHeader, unlocked int, byIdentity bool) []KeySlotInfo {
+	var result []KeySlotInfo
+	for i, s := range header.slots {
+		result = append(result, KeySlotInfo{Index: i, Label: s.label, Factors: int(s.factors), Unlocked: i == unlocked})
+	}
+	for i, r := range header.recipients {
+		recipient := FormatRecipient(r.recipient)
+		result = append(result, KeySlotInfo{Index: len(header.slots) + i, Label: r.label, Recipient: recipient,
+			Unlocked: byIdentity && r.recipient == identityPublic})
+	}
+	return result
+}
+
+// The document can be saved: either a password has been entered or the document was opened with an identity
+func HasDocumentKey() bool {
+	return valid || session.valid
+}
+
+func ensureSession() error {
+	if !session.valid {
+		if !valid {
+			return errors.New(assets.ErrNoKeySlots)
+		}
+		return newSession()
+	}
+	if session.rekey {
+		return rekeySession()
+	}
+	return nil
+}
+
+// Start over with a new content key and a single slot for the credentials in the enclave
+func ResetKeySlots() {
+	closeSession()
+}
+
+// Add a slot for another password and/or keyfile, the enclave is not changed
+func AddKeySlot(label string, password []byte, keyfile []byte) error {
+	if err := ensureSession(); err != nil {
+		return err
+	}
+	f := 0
+	var hash ShaResult
+	if len(password) > 0 {
+		f |= FactorPassword
+	}
+	if keyfile != nil {
+		f |= FactorKeyfile
+		sha := NewSha512()
+		hash = sha.Compute(keyfile)
+	}
+	if f == 0 {
+		return errors.New(assets.ErrNoKeySlots)
+	}
+	material := keyMaterial(f, password, hash[:])
+	s, err := newKeySlot(decode(session.key), byte(f), material)
+	clear(material)
+	if err != nil {
+		return err
+	}
+	s.label = label
+	session.slots = append(session.slots, s)
+	return nil
+}
+
+// The last slot or recipient cannot be revoked, otherwise the document could not be opened anymore
+func RevokeKeySlot(index int) error {
+	if !session.valid || index < 0 || index >= len(session.slots)+len(session.recipients) {
+		return errors.New(assets.ErrNoKeySlots)
+	}
+	if len(session.slots)+len(session.recipients) == 1 {
+		return errors.New(assets.ErrLastKeySlot)
+	}
+	if index >= len(session.slots) {
+		index -= len(session.slots)
+		session.recipients = slices.Delete(session.recipients, index, index+1)
+		return nil
+	}
+	session.slots = slices.Delete(session.slots, index, index+1)
+	switch {
+	case index < session.unlocked:
+		session.unlocked--
+	case index == session.unlocked:
+		// The credentials in the enclave no longer open the document, a new password gets a slot of its own
+		session.unlocked = -1
+		session.rekey = false
+	}
+	return nil
+}
+
+// Replace the header of a saved container by the slots of the session, the encrypted body is kept as is
+func RewriteKeySlots(payload []byte) ([]byte, string) {
+	if !session.valid || !isContainerV2(payload) {
+		return nil, assets.ErrCorrupted
+	}
+	_, rest, err := parseHeader(payload)
+	if err != nil || len(rest) < Sha512Shabytes+int(TwofishBlocksize) {
+		return nil, assets.ErrCorrupted
+	}
+	_, macKey := contentKeys(decode(session.key))
+	macPos := len(payload) - Sha512Shabytes
+	mac := HmacSha512(macKey[:], payload[:macPos])
+	if !hmac.Equal(mac[:], payload[macPos:]) {
+		return nil, assets.ErrCorrupted
+	}
+	outp := marshalHeader()
+	outp = append(outp, rest[:len(rest)-Sha512Shabytes]...)
+	mac = HmacSha512(macKey[:], outp)
+	return append(outp, mac[:]...), ""
+}
@@ -0,0 +1,137 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Document metadata: title, author, tags, timestamps and free-form properties
+// Stored as a section of the encrypted body, records (tag, uint32 length, value) like the header
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	metaTitle byte = iota + 1
+	metaAuthor
+	metaTag
+	metaCreated
+	metaModified
+	metaProperty
+)
+
+type Property struct {
+	Key   string
+	Value string
+}
+
+type Metadata struct {
+	Title      string
+	Author     string
+	Tags       []string
+	Created    time.Time
+	Modified   time.Time
+	Properties []Property
+}
+
+// Metadata of the open document
+var metadata Metadata
+
+func DocumentMetadata() Metadata {
+	m := metadata
+	m.Tags = slices.Clone(metadata.Tags)
+	m.Properties = slices.Clone(metadata.Properties)
+	return m
+}
+
+// Timestamps are kept, they are set when the document is saved
+func SetDocumentMetadata(m Metadata) {
+	metadata.Title = strings.TrimSpace(m.Title)
+	metadata.Author = strings.TrimSpace(m.Author)
+	metadata.Tags = nil
+	for _, t := range m.Tags {
+		if t = strings.TrimSpace(t); t != "" && !slices.Contains(metadata.Tags, t) {
+			metadata.Tags = append(metadata.Tags, t)
+		}
+	}
+	metadata.Properties = nil
+	for _, p := range m.Properties {
+		// The key ends at the first zero byte
+		p.Key = strings.TrimSpace(strings.ReplaceAll(p.Key, "\x00", ""))
+		if p.Key != "" {
+			metadata.Properties = append(metadata.Properties, Property{Key: p.Key, Value: strings.TrimSpace(p.Value)})
+		}
+	}
+}
+
+func resetMetadata() {
+	metadata = Metadata{}
+}
+
+func touchMetadata() {
+	now := time.Now()
+	if metadata.Created.IsZero() {
+		metadata.Created = now
+	}
+	metadata.Modified = now
+}
+
+func (m Metadata) marshal() []byte {
+	var b []byte
+	if m.Title != "" {
+		b = appendRecord(b, metaTitle, []byte(m.Title))
+	}
+	if m.Author != "" {
+		b = appendRecord(b, metaAuthor, []byte(m.Author))
+	}
+	for _, t := range m.Tags {
+		b = appendRecord(b, metaTag, []byte(t))
+	}
+	b = appendTime(b, metaCreated, m.Created)
+	b = appendTime(b, metaModified, m.Modified)
+	for _, p := range m.Properties {
+		b = appendRecord(b, metaProperty, []byte(p.Key+"\x00"+p.Value))
+	}
+	return b
+}
+
+func appendTime(b []byte, tag byte, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	return appendRecord(b, tag, binary.BigEndian.AppendUint64(nil, uint64(t.UnixMilli())))
+}
+
+// Unknown records are ignored
+func unmarshalMetadata(b []byte) (Metadata, error) {
+	var m Metadata
+	err := parseRecords(b, func(tag byte, value []byte) error {
+		switch tag {
+		case metaTitle:
+			m.Title = string(value)
+		case metaAuthor:
+			m.Author = string(value)
+		case metaTag:
+			m.Tags = append(m.Tags, string(value))
+		case metaCreated, metaModified:
+			if len(value) != 8 {
+				return errors.New(assets.ErrCorrupted)
+			}
+			t := time.UnixMilli(int64(binary.BigEndian.Uint64(value)))
+			if tag == metaCreated {
+				m.Created = t
+			} else {
+				m.Modified = t
+			}
+		case metaProperty:
+			key, value, _ := strings.Cut(string(value), "\x00")
+			m.Properties = append(m.Properties, Property{Key: key, Value: value})
+		}
+		return nil
+	})
+	return m, err
+}
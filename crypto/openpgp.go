@@ -0,0 +1,552 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// OpenPGP symmetrically encrypted messages (RFC 4880, gpg --symmetric) for import and export
+// SKESK v4 + SEIPD v1 (with MDC), optionally compressed, binary or ASCII armored
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"bytes"
+	"compress/bzip2"
+	"compress/flate"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/crypto/blowfish"
+	"golang.org/x/crypto/cast5"
+	"golang.org/x/crypto/ripemd160"
+	"hash"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	pgpTagSKESK      = 3
+	pgpTagSED        = 9
+	pgpTagCompressed = 8
+	pgpTagLiteral    = 11
+	pgpTagSEIPD      = 18
+	pgpTagAEAD       = 20
+)
+
+const (
+	pgpCipher3DES     = 2
+	pgpCipherCAST5    = 3
+	pgpCipherBlowfish = 4
+	pgpCipherAES128   = 7
+	pgpCipherAES192   = 8
+	pgpCipherAES256   = 9
+	pgpCipherTwofish  = 10
+)
+
+const (
+	pgpHashMD5       = 1
+	pgpHashSHA1      = 2
+	pgpHashRIPEMD160 = 3
+	pgpHashSHA256    = 8
+	pgpHashSHA384    = 9
+	pgpHashSHA512    = 10
+	pgpHashSHA224    = 11
+)
+
+const (
+	pgpArmorBegin = "-----BEGIN PGP MESSAGE-----"
+	pgpArmorEnd   = "-----END PGP MESSAGE-----"
+	pgpS2KCount   = 0xff // 65011712 bytes, as gpg uses for strong passphrases
+	pgpMDCLength  = 22
+)
+
+type pgpPacket struct {
+	tag  int
+	body []byte
+}
+
+// Twofish as cipher.Block, OpenPGP uses Twofish with 256 bit keys only
+type twofishBlock struct {
+	tf Twofish
+}
+
+func (b twofishBlock) BlockSize() int { return int(TwofishBlocksize) }
+
+func (b twofishBlock) Encrypt(dst, src []byte) {
+	var block TfBlock
+	copy(block[:], src)
+	b.tf.EncryptBlock(&block)
+	copy(dst, block[:])
+}
+
+func (b twofishBlock) Decrypt(dst, src []byte) {
+	var block TfBlock
+	copy(block[:], src)
+	b.tf.DecryptBlock(&block)
+	copy(dst, block[:])
+}
+
+func pgpKeySize(algo int) int {
+	switch algo {
+	case pgpCipher3DES, pgpCipherAES192:
+		return 24
+	case pgpCipherCAST5, pgpCipherBlowfish, pgpCipherAES128:
+		return 16
+	case pgpCipherAES256, pgpCipherTwofish:
+		return 32
+	}
+	return 0
+}
+
+func pgpNewCipher(algo int, key []byte) (cipher.Block, error) {
+	switch algo {
+	case pgpCipher3DES:
+		return des.NewTripleDESCipher(key)
+	case pgpCipherCAST5:
+		return cast5.NewCipher(key)
+	case pgpCipherBlowfish:
+		return blowfish.NewCipher(key)
+	case pgpCipherAES128, pgpCipherAES192, pgpCipherAES256:
+		return aes.NewCipher(key)
+	case pgpCipherTwofish:
+		var k TfKey
+		copy(k[:], key)
+		return twofishBlock{tf: NewTwofish(k)}, nil
+	}
+	return nil, errors.New(assets.ErrPgpUnsupported)
+}
+
+func pgpNewHash(algo int) hash.Hash {
+	switch algo {
+	case pgpHashMD5:
+		return md5.New()
+	case pgpHashSHA1:
+		return sha1.New()
+	case pgpHashRIPEMD160:
+		return ripemd160.New()
+	case pgpHashSHA256:
+		return sha256.New()
+	case pgpHashSHA384:
+		return sha512.New384()
+	case pgpHashSHA512:
+		return sha512.New()
+	case pgpHashSHA224:
+		return sha256.New224()
+	}
+	return nil
+}
+
+// CFB with zero IV as used by SEIPD and encrypted session keys (no resynchronization)
+func pgpCfb(block cipher.Block, data []byte, decrypt bool) []byte {
+	bs := block.BlockSize()
+	outp := make([]byte, len(data))
+	feedback := make([]byte, bs)
+	stream := make([]byte, bs)
+	for i := 0; i < len(data); i += bs {
+		block.Encrypt(stream, feedback)
+		n := min(bs, len(data)-i)
+		for j := 0; j < n; j++ {
+			outp[i+j] = data[i+j] ^ stream[j]
+		}
+		if decrypt {
+			copy(feedback, data[i:i+n])
+		} else {
+			copy(feedback, outp[i:i+n])
+		}
+	}
+	return outp
+}
+
+// String-to-key specifier: type, hash, salt and iteration count, returns the key and the length of the specifier
+func pgpS2K(spec []byte, passphrase []byte, keySize int) ([]byte, int, error) {
+	if len(spec) < 2 {
+		return nil, 0, errors.New(assets.ErrCorrupted)
+	}
+	h := pgpNewHash(int(spec[1]))
+	if h == nil {
+		return nil, 0, errors.New(assets.ErrPgpUnsupported)
+	}
+	var salt []byte
+	count := 0
+	length := 2
+	switch spec[0] {
+	case 0:
+	case 1:
+		length = 10
+	case 3:
+		length = 11
+	default:
+		return nil, 0, errors.New(assets.ErrPgpUnsupported)
+	}
+	if len(spec) < length {
+		return nil, 0, errors.New(assets.ErrCorrupted)
+	}
+	if spec[0] != 0 {
+		salt = spec[2:10]
+	}
+	input := append(append([]byte{}, salt...), passphrase...)
+	if spec[0] == 3 {
+		c := int(spec[10])
+		count = (16 + c&15) << (c>>4 + 6)
+	}
+	count = max(count, len(input))
+	var key []byte
+	for preload := 0; len(key) < keySize; preload++ {
+		h.Reset()
+		h.Write(make([]byte, preload))
+		for done := 0; done < count; {
+			n := min(len(input), count-done)
+			h.Write(input[:n])
+			done += n
+		}
+		key = h.Sum(key)
+	}
+	clear(input)
+	return key[:keySize], length, nil
+}
+
+func pgpReadPackets(data []byte) ([]pgpPacket, error) {
+	var packets []pgpPacket
+	for len(data) > 0 {
+		ctb := data[0]
+		if ctb&0x80 == 0 {
+			return nil, errors.New(assets.ErrCorrupted)
+		}
+		var tag int
+		var body []byte
+		var err error
+		if ctb&0x40 != 0 {
+			tag = int(ctb & 0x3f)
+			body, data, err = pgpNewFormatBody(data[1:])
+		} else {
+			tag = int(ctb>>2) & 0x0f
+			body, data, err = pgpOldFormatBody(data[1:], ctb&3)
+		}
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, pgpPacket{tag: tag, body: body})
+	}
+	return packets, nil
+}
+
+// New format lengths, partial body lengths are joined
+func pgpNewFormatBody(data []byte) ([]byte, []byte, error) {
+	var body []byte
+	for {
+		if len(data) < 1 {
+			return nil, nil, errors.New(assets.ErrCorrupted)
+		}
+		var l, skip int
+		partial := false
+		switch b := int(data[0]); {
+		case b < 192:
+			l, skip = b, 1
+		case b < 224:
+			if len(data) < 2 {
+				return nil, nil, errors.New(assets.ErrCorrupted)
+			}
+			l, skip = (b-192)<<8+int(data[1])+192, 2
+		case b < 255:
+			l, skip, partial = 1<<(b&0x1f), 1, true
+		default:
+			if len(data) < 5 {
+				return nil, nil, errors.New(assets.ErrCorrupted)
+			}
+			l, skip = int(binary.BigEndian.Uint32(data[1:5])), 5
+		}
+		data = data[skip:]
+		if l > len(data) {
+			return nil, nil, errors.New(assets.ErrCorrupted)
+		}
+		body = append(body, data[:l]...)
+		data = data[l:]
+		if !partial {
+			return body, data, nil
+		}
+	}
+}
+
+func pgpOldFormatBody(data []byte, lengthType byte) ([]byte, []byte, error) {
+	var l int
+	switch lengthType {
+	case 0:
+		if len(data) < 1 {
+			return nil, nil, errors.New(assets.ErrCorrupted)
+		}
+		l, data = int(data[0]), data[1:]
+	case 1:
+		if len(data) < 2 {
+			return nil, nil, errors.New(assets.ErrCorrupted)
+		}
+		l, data = int(binary.BigEndian.Uint16(data)), data[2:]
+	case 2:
+		if len(data) < 4 {
+			return nil, nil, errors.New(assets.ErrCorrupted)
+		}
+		l, data = int(binary.BigEndian.Uint32(data)), data[4:]
+	default:
+		// Indeterminate length: up to the end
+		return data, nil, nil
+	}
+	if l > len(data) {
+		return nil, nil, errors.New(assets.ErrCorrupted)
+	}
+	return data[:l], data[l:], nil
+}
+
+func pgpAppendPacket(b []byte, tag int, body []byte) []byte {
+	b = append(b, 0xc0|byte(tag), 0xff)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(body)))
+	return append(b, body...)
+}
+
+func isOpenPGPFile(data []byte) bool {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(pgpArmorBegin)) {
+		return true
+	}
+	packets, err := pgpReadPackets(data)
+	if err != nil || len(packets) == 0 {
+		return false
+	}
+	for _, p := range packets {
+		if p.tag == pgpTagSKESK {
+			return true
+		}
+	}
+	return false
+}
+
+// Encrypt text as gpg --symmetric does, with AES-256 or Twofish
+func encryptOpenPGP(payload []byte, passphrase []byte, algo int) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New(assets.ErrPassphraseRequired)
+	}
+	spec := make([]byte, 11)
+	spec[0], spec[1], spec[10] = 3, pgpHashSHA256, pgpS2KCount
+	if _, err := rand.Read(spec[2:10]); err != nil {
+		return nil, err
+	}
+	key, _, err := pgpS2K(spec, passphrase, pgpKeySize(algo))
+	if err != nil {
+		return nil, err
+	}
+	block, err := pgpNewCipher(algo, key)
+	clear(key)
+	if err != nil {
+		return nil, err
+	}
+	outp := pgpAppendPacket(nil, pgpTagSKESK, append([]byte{4, byte(algo)}, spec...))
+	literal := []byte{'b', 0}
+	literal = binary.BigEndian.AppendUint32(literal, uint32(time.Now().Unix()))
+	literal = pgpAppendPacket(nil, pgpTagLiteral, append(literal, payload...))
+	bs := block.BlockSize()
+	prefix := make([]byte, bs, bs+2)
+	if _, err = rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	prefix = append(prefix, prefix[bs-2:]...)
+	plain := append(append(prefix, literal...), 0xd3, 0x14)
+	mdc := sha1.Sum(plain)
+	plain = append(plain, mdc[:]...)
+	return pgpAppendPacket(outp, pgpTagSEIPD, append([]byte{1}, pgpCfb(block, plain, false)...)), nil
+}
+
+func decryptOpenPGP(data []byte, passphrase []byte) ([]byte, string) {
+	data, message := pgpDearmor(data)
+	if message != "" {
+		return nil, message
+	}
+	packets, err := pgpReadPackets(data)
+	if err != nil {
+		return nil, assets.ErrCorrupted
+	}
+	var skesks [][]byte
+	for _, p := range packets {
+		switch p.tag {
+		case pgpTagSKESK:
+			skesks = append(skesks, p.body)
+		case pgpTagSED, pgpTagAEAD:
+			return nil, assets.ErrPgpUnsupported
+		case pgpTagSEIPD:
+			if len(skesks) == 0 {
+				return nil, assets.ErrPgpPublicKey
+			}
+			if len(passphrase) == 0 {
+				return nil, assets.ErrPassphraseRequired
+			}
+			return pgpDecryptSEIPD(p.body, skesks, passphrase)
+		}
+	}
+	return nil, assets.ErrCorrupted
+}
+
+// Every SKESK is tried, the quick check of the SEIPD prefix tells if the passphrase fits
+func pgpDecryptSEIPD(body []byte, skesks [][]byte, passphrase []byte) ([]byte, string) {
+	if len(body) < 1 || body[0] != 1 {
+		return nil, assets.ErrPgpUnsupported
+	}
+	body = body[1:]
+	message := assets.ErrUnableToDecrypt
+	for _, s := range skesks {
+		if len(s) < 2 || s[0] != 4 {
+			message = assets.ErrPgpUnsupported
+			continue
+		}
+		algo := int(s[1])
+		keySize := pgpKeySize(algo)
+		if keySize == 0 {
+			message = assets.ErrPgpUnsupported
+			continue
+		}
+		key, l, err := pgpS2K(s[2:], passphrase, keySize)
+		if err != nil {
+			message = err.Error()
+			continue
+		}
+		// An encrypted session key follows the S2K specifier if the S2K key is not used directly
+		if esk := s[2+l:]; len(esk) > 0 {
+			block, err := pgpNewCipher(algo, key)
+			if err != nil {
+				continue
+			}
+			sessionKey := pgpCfb(block, esk, true)
+			algo = int(sessionKey[0])
+			key = sessionKey[1:]
+			if len(key) != pgpKeySize(algo) {
+				continue
+			}
+		}
+		block, err := pgpNewCipher(algo, key)
+		clear(key)
+		if err != nil {
+			message = assets.ErrPgpUnsupported
+			continue
+		}
+		bs := block.BlockSize()
+		if len(body) < bs+2+pgpMDCLength {
+			return nil, assets.ErrCorrupted
+		}
+		plain := pgpCfb(block, body, true)
+		if plain[bs-2] != plain[bs] || plain[bs-1] != plain[bs+1] {
+			continue
+		}
+		mdcPos := len(plain) - pgpMDCLength
+		mdc := sha1.Sum(plain[:mdcPos+2])
+		if plain[mdcPos] != 0xd3 || plain[mdcPos+1] != 0x14 || !bytes.Equal(mdc[:], plain[mdcPos+2:]) {
+			return nil, assets.ErrCorrupted
+		}
+		return pgpLiteralData(plain[bs+2 : mdcPos])
+	}
+	return nil, message
+}
+
+// The literal data packet may be compressed, signature packets are ignored
+func pgpLiteralData(data []byte) ([]byte, string) {
+	packets, err := pgpReadPackets(data)
+	if err != nil {
+		return nil, assets.ErrCorrupted
+	}
+	for _, p := range packets {
+		switch p.tag {
+		case pgpTagCompressed:
+			inflated, err := pgpDecompress(p.body)
+			if err != nil {
+				return nil, assets.ErrCorrupted
+			}
+			return pgpLiteralData(inflated)
+		case pgpTagLiteral:
+			if len(p.body) < 2 || len(p.body) < 2+int(p.body[1])+4 {
+				return nil, assets.ErrCorrupted
+			}
+			return p.body[2+int(p.body[1])+4:], ""
+		}
+	}
+	return nil, assets.ErrCorrupted
+}
+
+func pgpDecompress(body []byte) ([]byte, error) {
+	if len(body) < 1 {
+		return nil, errors.New(assets.ErrCorrupted)
+	}
+	var r io.Reader
+	var err error
+	switch body[0] {
+	case 0:
+		return body[1:], nil
+	case 1:
+		r = flate.NewReader(bytes.NewReader(body[1:]))
+	case 2:
+		r, err = zlib.NewReader(bytes.NewReader(body[1:]))
+	case 3:
+		r = bzip2.NewReader(bytes.NewReader(body[1:]))
+	default:
+		return nil, errors.New(assets.ErrPgpUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// ASCII armor: header lines, empty line, base64 data and an optional CRC-24 checksum
+func pgpDearmor(data []byte) ([]byte, string) {
+	text := strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n"))
+	if !strings.HasPrefix(text, pgpArmorBegin) {
+		return data, ""
+	}
+	end := strings.Index(text, pgpArmorEnd)
+	if end < 0 {
+		return nil, assets.ErrCorrupted
+	}
+	lines := strings.Split(strings.TrimSpace(text[len(pgpArmorBegin):end]), "\n")
+	var sb strings.Builder
+	var checksum string
+	inHeader := true
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case inHeader && strings.Contains(line, ": "):
+		case inHeader && line == "":
+			inHeader = false
+		case strings.HasPrefix(line, "="):
+			checksum = line[1:]
+		default:
+			inHeader = false
+			sb.WriteString(line)
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(sb.String())
+	if err != nil {
+		return nil, assets.ErrCorrupted
+	}
+	if checksum != "" {
+		crc, err := base64.StdEncoding.DecodeString(checksum)
+		if err != nil || len(crc) != 3 || uint32(crc[0])<<16|uint32(crc[1])<<8|uint32(crc[2]) != pgpCrc24(decoded) {
+			return nil, assets.ErrCorrupted
+		}
+	}
+	return decoded, ""
+}
+
+func pgpCrc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
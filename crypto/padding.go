@@ -0,0 +1,72 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Length hiding: the encrypted part is padded to bucketed sizes, so the file size tells less about the text
+// Padding follows the body, which ends with sectionEnd (or the end of the DEFLATE stream) and is ignored on reading
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+const (
+	PaddingNone = iota
+	PaddingPadme
+	PaddingPowerOfTwo
+	PaddingBlock
+)
+
+const paddingBlockSize = 4096
+
+// Padding of the open document, stored in the body
+var padding = PaddingNone
+
+func DocumentPadding() int {
+	return padding
+}
+
+func SetDocumentPadding(p int) {
+	padding = p
+}
+
+// Size of the encrypted part for a given size of the plain part, CBC adds 1 to 16 bytes
+func cipherSize(l int) int {
+	return (l/int(TwofishBlocksize) + 1) * int(TwofishBlocksize)
+}
+
+func bucketSize(size int, mode int) int {
+	switch mode {
+	case PaddingPadme:
+		// Padmé: keep the most significant bits of the size, at most 12% overhead
+		if size < 2 {
+			return size
+		}
+		e := bitLength(size) - 1
+		s := bitLength(e)
+		mask := 1<<(e-s) - 1
+		return (size + mask) &^ mask
+	case PaddingPowerOfTwo:
+		return 1 << bitLength(size-1)
+	case PaddingBlock:
+		return (size + paddingBlockSize - 1) / paddingBlockSize * paddingBlockSize
+	}
+	return size
+}
+
+// Number of bits needed to represent n
+func bitLength(n int) int {
+	l := 0
+	for ; n > 0; n >>= 1 {
+		l++
+	}
+	return l
+}
+
+// Zeros to append to the plain part of length l
+func paddingSize(l int, mode int) int {
+	if mode == PaddingNone {
+		return 0
+	}
+	size := cipherSize(l)
+	bucket := bucketSize(size, mode)
+	// Buckets are multiples of the block size, the last byte is taken by the CBC padding
+	bucket = (bucket + int(TwofishBlocksize) - 1) / int(TwofishBlocksize) * int(TwofishBlocksize)
+	return bucket - 1 - l
+}
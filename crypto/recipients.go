@@ -0,0 +1,226 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Public key recipients: the content key is wrapped for X25519 public keys (age style recipient stanzas)
+//
+// recipient stanza: ephemeral public key | recipient public key | wrapped content key | check | label
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	x25519KeySize = 32
+	recipientSize = 2*x25519KeySize + int(TwofishKeysize) + slotCheckLen
+	recipientHrp  = "age"
+	identityHrp   = "AGE-SECRET-KEY-"
+)
+
+type recipientStanza struct {
+	ephemeral [x25519KeySize]byte
+	recipient [x25519KeySize]byte
+	wrapped   [TwofishKeysize]byte
+	check     [slotCheckLen]byte
+	label     string
+}
+
+// The private key of the identity loaded is kept obscured like the password
+var identityVault []byte
+var identityPublic [x25519KeySize]byte
+
+func newRecipientStanza(key TfKey, recipient [x25519KeySize]byte) (recipientStanza, error) {
+	r := recipientStanza{recipient: recipient}
+	pub, err := ecdh.X25519().NewPublicKey(recipient[:])
+	if err != nil {
+		return r, err
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return r, err
+	}
+	shared, err := ephemeral.ECDH(pub)
+	if err != nil {
+		return r, err
+	}
+	copy(r.ephemeral[:], ephemeral.PublicKey().Bytes())
+	wrapKey, checkKey := deriveRecipientKeys(shared, r.ephemeral, r.recipient)
+	clear(shared)
+	r.wrapped, r.check = wrapContentKey(key, wrapKey, checkKey)
+	return r, nil
+}
+
+func deriveRecipientKeys(shared []byte, ephemeral, recipient [x25519KeySize]byte) (TfKey, TfKey) {
+	var wrapKey, checkKey TfKey
+	info := append(append([]byte("recipient"), ephemeral[:]...), recipient[:]...)
+	kek := HmacSha512(shared, info)
+	copy(wrapKey[:], kek[:TwofishKeysize])
+	copy(checkKey[:], kek[TwofishKeysize:])
+	return wrapKey, checkKey
+}
+
+func (r recipientStanza) unwrap(identity *ecdh.PrivateKey) (TfKey, bool) {
+	var key TfKey
+	pub, err := ecdh.X25519().NewPublicKey(r.ephemeral[:])
+	if err != nil {
+		return key, false
+	}
+	shared, err := identity.ECDH(pub)
+	if err != nil {
+		return key, false
+	}
+	wrapKey, checkKey := deriveRecipientKeys(shared, r.ephemeral, r.recipient)
+	clear(shared)
+	return unwrapContentKey(r.wrapped, r.check, wrapKey, checkKey)
+}
+
+func (r recipientStanza) marshal() []byte {
+	b := make([]byte, 0, recipientSize+len(r.label))
+	b = append(b, r.ephemeral[:]...)
+	b = append(b, r.recipient[:]...)
+	b = append(b, r.wrapped[:]...)
+	b = append(b, r.check[:]...)
+	return append(b, r.label...)
+}
+
+func unmarshalRecipient(b []byte) (recipientStanza, bool) {
+	var r recipientStanza
+	if len(b) < recipientSize {
+		return r, false
+	}
+	r.label = string(b[recipientSize:])
+	copy(r.ephemeral[:], b[:x25519KeySize])
+	b = b[x25519KeySize:]
+	copy(r.recipient[:], b[:x25519KeySize])
+	b = b[x25519KeySize:]
+	copy(r.wrapped[:], b[:TwofishKeysize])
+	copy(r.check[:], b[TwofishKeysize:TwofishKeysize+slotCheckLen])
+	return r, true
+}
+
+func unwrapWithIdentity(recipients []recipientStanza) (TfKey, bool) {
+	var key TfKey
+	identity := popIdentity()
+	if identity == nil {
+		return key, false
+	}
+	for _, r := range recipients {
+		if r.recipient == identityPublic {
+			return r.unwrap(identity)
+		}
+	}
+	return key, false
+}
+
+func popIdentity() *ecdh.PrivateKey {
+	if identityVault == nil {
+		return nil
+	}
+	b := rotate(identityVault, rol)
+	identity, err := ecdh.X25519().NewPrivateKey(b)
+	clear(b)
+	if err != nil {
+		return nil
+	}
+	return identity
+}
+
+func pushIdentity(identity *ecdh.PrivateKey) {
+	clear(identityVault)
+	identityVault = rotate(identity.Bytes(), ror)
+	copy(identityPublic[:], identity.PublicKey().Bytes())
+}
+
+// Create a new identity and keep it in memory, returns the public key to share
+func GenerateIdentity() (string, error) {
+	identity, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	pushIdentity(identity)
+	return IdentityPublicKey(), nil
+}
+
+func IdentityLoaded() bool {
+	return identityVault != nil
+}
+
+func IdentityPublicKey() string {
+	if !IdentityLoaded() {
+		return ""
+	}
+	return FormatRecipient(identityPublic)
+}
+
+func ForgetIdentity() {
+	clear(identityVault)
+	identityVault = nil
+	identityPublic = [x25519KeySize]byte{}
+}
+
+func FormatRecipient(recipient [x25519KeySize]byte) string {
+	s, _ := bech32Encode(recipientHrp, recipient[:])
+	return s
+}
+
+func ParseRecipient(s string) ([x25519KeySize]byte, error) {
+	var recipient [x25519KeySize]byte
+	hrp, data, err := bech32Decode(strings.TrimSpace(s))
+	if err != nil || hrp != recipientHrp || len(data) != x25519KeySize {
+		return recipient, errors.New(assets.ErrInvalidRecipient)
+	}
+	if _, err = ecdh.X25519().NewPublicKey(data); err != nil {
+		return recipient, errors.New(assets.ErrInvalidRecipient)
+	}
+	copy(recipient[:], data)
+	return recipient, nil
+}
+
+// Add a recipient stanza to the open document
+func AddRecipient(label string, publicKey string) error {
+	recipient, err := ParseRecipient(publicKey)
+	if err != nil {
+		return err
+	}
+	if err = ensureSession(); err != nil {
+		return err
+	}
+	for _, r := range session.recipients {
+		if r.recipient == recipient {
+			return errors.New(assets.ErrDuplicateRecipient)
+		}
+	}
+	r, err := newRecipientStanza(decode(session.key), recipient)
+	if err != nil {
+		return err
+	}
+	r.label = label
+	session.recipients = append(session.recipients, r)
+	return nil
+}
+
+// True if the identity loaded is one of the recipients of the container
+func UnlocksWithIdentity(payload []byte) bool {
+	if !IdentityLoaded() || !isContainerV2(payload) {
+		return false
+	}
+	header, _, err := parseHeader(payload)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(header.recipients, func(r recipientStanza) bool { return r.recipient == identityPublic })
+}
+
+func HasRecipients(payload []byte) bool {
+	if !isContainerV2(payload) {
+		return false
+	}
+	header, _, err := parseHeader(payload)
+	return err == nil && len(header.recipients) > 0
+}
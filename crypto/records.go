@@ -0,0 +1,108 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Structured secrets: records with title, username, password, URL, notes and custom fields
+// One section of the encrypted body per record, the text of the document is kept as well
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	recTitle byte = iota + 1
+	recUsername
+	recPassword
+	recURL
+	recNotes
+	recField
+)
+
+type RecordField struct {
+	Name  string
+	Value string
+}
+
+type Record struct {
+	Title    string
+	Username string
+	Password string
+	URL      string
+	Notes    string
+	Fields   []RecordField
+}
+
+// Records of the open document
+var records []Record
+
+func DocumentRecords() []Record {
+	result := slices.Clone(records)
+	for i := range result {
+		result[i].Fields = slices.Clone(records[i].Fields)
+	}
+	return result
+}
+
+// Custom fields without name and value are dropped
+func SetDocumentRecords(r []Record) {
+	records = nil
+	for _, rec := range r {
+		rec.Title = strings.TrimSpace(rec.Title)
+		fields := rec.Fields
+		rec.Fields = nil
+		for _, f := range fields {
+			// The name ends at the first zero byte
+			f.Name = strings.TrimSpace(strings.ReplaceAll(f.Name, "\x00", ""))
+			if f.Name != "" || f.Value != "" {
+				rec.Fields = append(rec.Fields, f)
+			}
+		}
+		records = append(records, rec)
+	}
+}
+
+func resetRecords() {
+	records = nil
+}
+
+func (r Record) marshal() []byte {
+	var b []byte
+	for _, v := range []struct {
+		tag   byte
+		value string
+	}{{recTitle, r.Title}, {recUsername, r.Username}, {recPassword, r.Password}, {recURL, r.URL}, {recNotes, r.Notes}} {
+		if v.value != "" {
+			b = appendRecord(b, v.tag, []byte(v.value))
+		}
+	}
+	for _, f := range r.Fields {
+		b = appendRecord(b, recField, []byte(f.Name+"\x00"+f.Value))
+	}
+	return b
+}
+
+// Unknown records are ignored
+func unmarshalRecord(b []byte) (Record, error) {
+	var r Record
+	err := parseRecords(b, func(tag byte, value []byte) error {
+		switch tag {
+		case recTitle:
+			r.Title = string(value)
+		case recUsername:
+			r.Username = string(value)
+		case recPassword:
+			r.Password = string(value)
+		case recURL:
+			r.URL = string(value)
+		case recNotes:
+			r.Notes = string(value)
+		case recField:
+			name, value, _ := strings.Cut(string(value), "\x00")
+			r.Fields = append(r.Fields, RecordField{Name: name, Value: value})
+		}
+		return nil
+	})
+	return r, err
+}
@@ -0,0 +1,62 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Search the text of encrypted files in memory, the state of the open document is not affected
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"regexp"
+	"strings"
+)
+
+type SearchMatch struct {
+	Line   int // starting at 1
+	Text   string
+	Before []string
+	After  []string
+}
+
+// Search a file with the password and/or keyfile given, both may be nil if the vault opened or the identity
+// loaded opens the file
+func SearchPayload(payload []byte, password []byte, keyfile []byte, re *regexp.Regexp, context int) ([]SearchMatch, string) {
+	state := saveState()
+	defer state.restore()
+	clear(pwdVault)
+	vault, pwdVault, keyfileVault, factors = [TwofishKeysize]byte{}, nil, ShaResult{}, 0
+	PushCredentials(payload, password, keyfile)
+	text, message := DecryptPayload(payload)
+	if message != "" {
+		return nil, message
+	}
+	return SearchText(text, re, context), ""
+}
+
+// Lines matching, with up to context lines before and after each match
+func SearchText(text string, re *regexp.Regexp, context int) []SearchMatch {
+	var result []SearchMatch
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		m := SearchMatch{Line: i + 1, Text: line}
+		if context > 0 {
+			m.Before = lines[max(i-context, 0):i]
+			m.After = lines[i+1 : min(i+1+context, len(lines))]
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// The pattern is taken literally unless regex is set
+func SearchPattern(pattern string, regex bool, ignoreCase bool) (*regexp.Regexp, error) {
+	if !regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
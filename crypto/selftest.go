@@ -1,6 +1,6 @@
 //----------------------------------------------------------------------------------------------------------------------
 // (w) 2023-2024 by Jan Buchholz
-// Self tests for Sha512, Blowfish and Twofish
+// Self tests for Sha512, HMAC-SHA512, Blowfish and Twofish
 //----------------------------------------------------------------------------------------------------------------------
 
 package crypto
@@ -8,7 +8,7 @@ package crypto
 import "slices"
 
 func SelfTest() bool {
-	return checkTwofish() && checkSha512()
+	return checkTwofish() && checkSha512() && checkHmacSha512()
 }
 
 func checkTwofish() bool {
@@ -56,3 +56,14 @@ func checkSha512() bool {
 	res := sha.Compute(inp)
 	return slices.Equal(check[:], res[:])
 }
+
+// RFC 4231, test case 2
+func checkHmacSha512() bool {
+	var check = ShaResult{
+		0x16, 0x4b, 0x7a, 0x7b, 0xfc, 0xf8, 0x19, 0xe2, 0xe3, 0x95, 0xfb, 0xe7, 0x3b, 0x56, 0xe0, 0xa3,
+		0x87, 0xbd, 0x64, 0x22, 0x2e, 0x83, 0x1f, 0xd6, 0x10, 0x27, 0x0c, 0xd7, 0xea, 0x25, 0x05, 0x54,
+		0x97, 0x58, 0xbf, 0x75, 0xc0, 0x5a, 0x99, 0x4a, 0x6d, 0x03, 0x4f, 0x65, 0xf8, 0xf0, 0xe6, 0xfd,
+		0xca, 0xea, 0xb1, 0xa3, 0x4d, 0x4a, 0x6b, 0x4b, 0x63, 0x6e, 0x07, 0x0a, 0x38, 0xbc, 0xe7, 0x37}
+	res := HmacSha512([]byte("Jefe"), []byte("what do ya want for nothing?"))
+	return slices.Equal(check[:], res[:])
+}
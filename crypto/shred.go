@@ -0,0 +1,90 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Secure delete of plain text files: overwrite several times, truncate, rename and unlink
+// On SSDs and journaling or copy-on-write file systems copies of the data may survive
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const (
+	ShredPasses     = 3
+	shredBufferSize = 64 * 1024
+)
+
+// Overwrite the content of a regular file with random bytes, then truncate, rename and delete it
+func ShredFile(p string, passes int) error {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return errors.New(assets.ErrNotRegularFile)
+	}
+	file, err := os.OpenFile(p, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < max(passes, 1) && err == nil; i++ {
+		err = overwrite(file, info.Size())
+	}
+	if err == nil {
+		if err = file.Truncate(0); err == nil {
+			err = file.Sync()
+		}
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	// The name may tell what the file was about
+	name, err := shredName(p)
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(p, name); err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+func overwrite(file *os.File, size int64) error {
+	buf := make([]byte, shredBufferSize)
+	for pos := int64(0); pos < size; {
+		n := int(min(size-pos, shredBufferSize))
+		if _, err := rand.Read(buf[:n]); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt(buf[:n], pos); err != nil {
+			return err
+		}
+		pos += int64(n)
+	}
+	return file.Sync()
+}
+
+// A random name in the same folder, existing files must not be replaced
+func shredName(p string) (string, error) {
+	dir, base := filepath.Split(p)
+	size := max(len(base), 16)
+	b := make([]byte, (size+1)/2)
+	for {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		name := filepath.Join(dir, hex.EncodeToString(b)[:size])
+		if _, err := os.Lstat(name); errors.Is(err, os.ErrNotExist) {
+			return name, nil
+		}
+	}
+}
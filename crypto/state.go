@@ -0,0 +1,87 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Snapshot of the enclave and the open document, restored if another file cannot be opened
+// or if the copy written by Save As fails
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import "slices"
+
+// Everything a decryption changes
+type cryptoState struct {
+	vault        [TwofishKeysize]byte
+	pwdVault     []byte
+	keyfileVault ShaResult
+	factors      int
+	valid        bool
+	session      containerSession
+	metadata     Metadata
+	attachments  []attachment
+	records      []Record
+	padding      int
+}
+
+// Taken before the credentials of another file are pushed, a mistyped password must not replace the ones
+// of the open document
+type State struct {
+	state cryptoState
+}
+
+func SaveState() State {
+	return State{state: saveState()}
+}
+
+// The file could not be opened, the open document is used again
+func (s State) Restore() {
+	s.state.restore()
+}
+
+// The file has been opened, the copies of the previous document are cleared
+func (s State) Discard() {
+	s.state.discard()
+}
+
+// Taken before Save As pushes a new password, the original keeps its key slots if the copy is not written
+type Credentials struct {
+	state cryptoState
+}
+
+func SaveCredentials() Credentials {
+	s := cryptoState{vault: vault, pwdVault: slices.Clone(pwdVault), keyfileVault: keyfileVault, factors: factors,
+		valid: valid, session: session}
+	s.session.slots = slices.Clone(session.slots)
+	return Credentials{state: s}
+}
+
+func (c Credentials) Restore() {
+	clear(pwdVault)
+	s := c.state
+	vault, pwdVault, keyfileVault, factors, valid, session = s.vault, s.pwdVault, s.keyfileVault, s.factors, s.valid, s.session
+}
+
+func (c Credentials) Discard() {
+	clear(c.state.pwdVault)
+}
+
+func saveState() cryptoState {
+	s := cryptoState{vault: vault, pwdVault: slices.Clone(pwdVault), keyfileVault: keyfileVault, factors: factors,
+		valid: valid, session: session, metadata: metadata, attachments: attachments, records: records, padding: padding}
+	// resetAttachments clears the buffers, it must not hit the saved ones
+	attachments = nil
+	return s
+}
+
+func (s cryptoState) restore() {
+	clear(pwdVault)
+	resetAttachments()
+	vault, pwdVault, keyfileVault, factors, valid = s.vault, s.pwdVault, s.keyfileVault, s.factors, s.valid
+	session, metadata, attachments, records, padding = s.session, s.metadata, s.attachments, s.records, s.padding
+}
+
+func (s cryptoState) discard() {
+	clear(s.pwdVault)
+	for _, a := range s.attachments {
+		clear(a.data)
+	}
+}
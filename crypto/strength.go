@@ -0,0 +1,150 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Estimate password strength (entropy, character classes, dictionary check)
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	ClassLower = 1 << iota
+	ClassUpper
+	ClassDigit
+	ClassSymbol
+	ClassOther
+)
+
+const (
+	StrengthWeak = iota
+	StrengthFair
+	StrengthGood
+	StrengthStrong
+)
+
+const minDictionaryBase = 3
+
+type PasswordStrength struct {
+	Length  int
+	Classes int
+	Entropy float64
+	Known   bool
+}
+
+var weakPasswords map[string]bool
+
+var leetReplacer = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s", "!", "i")
+
+func EstimatePasswordStrength(p string) PasswordStrength {
+	var s PasswordStrength
+	runes := []rune(p)
+	s.Length = len(runes)
+	if s.Length == 0 {
+		return s
+	}
+	for _, r := range runes {
+		s.Classes |= classOf(r)
+	}
+	s.Entropy = float64(s.Length) * math.Log2(float64(poolSize(s.Classes)))
+	// Repeated characters add little entropy
+	distinct := make(map[rune]bool)
+	for _, r := range runes {
+		distinct[r] = true
+	}
+	if len(distinct) < s.Length {
+		s.Entropy *= float64(len(distinct)) / float64(s.Length)
+	}
+	// zxcvbn-style: a dictionary word with leet substitutions and decorations is as strong as its decorations
+	if base, decoration := splitDecoration(p); isKnownWeak(base) {
+		s.Known = true
+		s.Entropy = math.Log2(float64(len(weakPasswords)))
+		if decoration > 0 {
+			s.Entropy += float64(decoration) * math.Log2(float64(poolSize(ClassDigit|ClassSymbol)))
+		}
+	}
+	return s
+}
+
+// Number of character classes used
+func (s PasswordStrength) ClassCount() int {
+	n := 0
+	for c := s.Classes; c != 0; c &= c - 1 {
+		n++
+	}
+	return n
+}
+
+func (s PasswordStrength) Rating() int {
+	switch {
+	case s.Known || s.Entropy < 40:
+		return StrengthWeak
+	case s.Entropy < 60:
+		return StrengthFair
+	case s.Entropy < 80:
+		return StrengthGood
+	}
+	return StrengthStrong
+}
+
+func classOf(r rune) int {
+	switch {
+	case r > unicode.MaxASCII:
+		return ClassOther
+	case unicode.IsLower(r):
+		return ClassLower
+	case unicode.IsUpper(r):
+		return ClassUpper
+	case unicode.IsDigit(r):
+		return ClassDigit
+	}
+	return ClassSymbol
+}
+
+func poolSize(classes int) int {
+	size := 0
+	if classes&ClassLower != 0 {
+		size += 26
+	}
+	if classes&ClassUpper != 0 {
+		size += 26
+	}
+	if classes&ClassDigit != 0 {
+		size += 10
+	}
+	if classes&ClassSymbol != 0 {
+		size += 33
+	}
+	if classes&ClassOther != 0 {
+		size += 100
+	}
+	return max(size, 1)
+}
+
+// Strip leading/trailing digits and symbols, returns the remaining word and the number of runes stripped
+func splitDecoration(p string) (string, int) {
+	isDecoration := func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}
+	base := strings.TrimRightFunc(strings.TrimLeftFunc(p, isDecoration), isDecoration)
+	if len([]rune(base)) < minDictionaryBase {
+		base = p
+	}
+	return base, len([]rune(p)) - len([]rune(base))
+}
+
+func isKnownWeak(p string) bool {
+	if weakPasswords == nil {
+		weakPasswords = make(map[string]bool)
+		for _, w := range strings.Fields(assets.WeakPasswords) {
+			weakPasswords[w] = true
+		}
+	}
+	lower := strings.ToLower(p)
+	return weakPasswords[lower] || weakPasswords[leetReplacer.Replace(lower)]
+}
@@ -0,0 +1,127 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Time-based one-time passwords (RFC 6238) from otpauth:// URIs or base32 secrets, computed locally
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	totpDefaultDigits = 6
+	totpDefaultPeriod = 30
+)
+
+type TOTP struct {
+	Issuer  string
+	Account string
+	secret  []byte
+	hash    func() hash.Hash
+	digits  int
+	period  int
+}
+
+var otpauthPattern = regexp.MustCompile(`otpauth://totp/[^\s"'<>]+`)
+
+// All otpauth URIs of a text, in the order they appear
+func FindOTPAuth(text string) []string {
+	return otpauthPattern.FindAllString(text, -1)
+}
+
+// otpauth://totp/Issuer:account?secret=BASE32&issuer=Issuer&algorithm=SHA1&digits=6&period=30, or just the secret
+func ParseTOTP(s string) (TOTP, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(strings.ToLower(s), "otpauth://") {
+		return newTOTP(s, "", "", "")
+	}
+	u, err := url.Parse(s)
+	if err != nil || !strings.EqualFold(u.Host, "totp") {
+		return TOTP{}, errors.New(assets.ErrTOTPInvalid)
+	}
+	q := u.Query()
+	t, err := newTOTP(q.Get("secret"), q.Get("algorithm"), q.Get("digits"), q.Get("period"))
+	if err != nil {
+		return t, err
+	}
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		t.Issuer, t.Account = strings.TrimSpace(issuer), strings.TrimSpace(account)
+	} else {
+		t.Account = strings.TrimSpace(label)
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		t.Issuer = issuer
+	}
+	return t, nil
+}
+
+func newTOTP(secret string, algorithm string, digits string, period string) (TOTP, error) {
+	t := TOTP{hash: sha1.New, digits: totpDefaultDigits, period: totpDefaultPeriod}
+	// Secrets are often written in groups and lower case, padding is optional
+	secret = strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "=", "").Replace(secret))
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return t, errors.New(assets.ErrTOTPSecret)
+	}
+	t.secret = key
+	switch strings.ToUpper(algorithm) {
+	case "", "SHA1":
+	case "SHA256":
+		t.hash = sha256.New
+	case "SHA512":
+		t.hash = sha512.New
+	default:
+		return t, errors.New(assets.ErrTOTPInvalid)
+	}
+	if digits != "" {
+		if t.digits, err = strconv.Atoi(digits); err != nil || t.digits < 6 || t.digits > 8 {
+			return t, errors.New(assets.ErrTOTPInvalid)
+		}
+	}
+	if period != "" {
+		if t.period, err = strconv.Atoi(period); err != nil || t.period < 1 {
+			return t, errors.New(assets.ErrTOTPInvalid)
+		}
+	}
+	return t, nil
+}
+
+// The code valid at the time given
+func (t TOTP) Code(now time.Time) string {
+	counter := uint64(now.Unix()) / uint64(t.period)
+	mac := hmac.New(t.hash, t.secret)
+	mac.Write(binary.BigEndian.AppendUint64(nil, counter))
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < t.digits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", t.digits, value%modulo)
+}
+
+// Seconds until the code changes
+func (t TOTP) Remaining(now time.Time) int {
+	return t.period - int(now.Unix()%int64(t.period))
+}
+
+func (t TOTP) Period() int {
+	return t.period
+}
@@ -438,7 +438,7 @@ func (tfish Twofish) CbcDecrypt(p []byte) []byte {
 		// Cut off trailing bytes if necessary
 		k = uint32(len(outp))
 		b = uint32(outp[k-1])
-		if b < TwofishBlocksize && b < k {
+		if b > 0 && b <= TwofishBlocksize && b <= k {
 			outp = outp[:k-b]
 		}
 	}
@@ -0,0 +1,266 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Vault: a folder of documents opened with one password
+// The manifest is a container whose content key is the master key, its body holds the names of the documents.
+// Documents are named by random ids and carry their content key wrapped with the master key.
+//----------------------------------------------------------------------------------------------------------------------
+
+package crypto
+
+import (
+	"SimpleTwofishEditor/assets"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	entryID byte = iota + 1
+	entryName
+)
+
+const vaultIDSize = 16
+
+type VaultEntry struct {
+	ID   string
+	Name string
+}
+
+// The manifest has a session of its own, the session of the open document is not affected
+type folderVault struct {
+	session containerSession
+	entries []VaultEntry
+	open    bool
+}
+
+var docVault folderVault
+
+// Run fn with the session given as session of the open document
+func withSession(s *containerSession, fn func()) {
+	session, *s = *s, session
+	defer func() { session, *s = *s, session }()
+	fn()
+}
+
+func VaultIsOpen() bool {
+	return docVault.open
+}
+
+// A new vault is protected by the credentials in the enclave
+func CreateVault() ([]byte, error) {
+	var err error
+	var s containerSession
+	withSession(&s, func() { err = newSession() })
+	if err != nil {
+		return nil, err
+	}
+	docVault = folderVault{session: s, open: true}
+	return VaultManifest()
+}
+
+func UnlockVault(manifest []byte) string {
+	if !isContainerV2(manifest) {
+		return assets.ErrNoVault
+	}
+	var s containerSession
+	var body []byte
+	var message string
+	withSession(&s, func() { body, message = decryptContainerV2(manifest) })
+	if message != "" {
+		return message
+	}
+	entries, err := parseManifest(body)
+	if err != nil {
+		return err.Error()
+	}
+	docVault = folderVault{session: s, entries: entries, open: true}
+	return ""
+}
+
+func CloseVault() {
+	docVault = folderVault{}
+}
+
+func VaultManifest() ([]byte, error) {
+	if !docVault.open {
+		return nil, errors.New(assets.ErrNoVault)
+	}
+	var body []byte
+	for _, e := range docVault.entries {
+		body = appendRecord(body, sectionVaultEntry, appendRecord(appendRecord(nil, entryID, []byte(e.ID)), entryName, []byte(e.Name)))
+	}
+	body = appendRecord(body, sectionEnd, nil)
+	var outp []byte
+	var err error
+	withSession(&docVault.session, func() {
+		// Neither compression nor padding for the manifest
+		c, p := compression, padding
+		compression, padding = CompressionNone, PaddingNone
+		outp, err = encryptContainerV2(body)
+		compression, padding = c, p
+	})
+	return outp, err
+}
+
+// The manifest is written without padding, it ends with sectionEnd
+func parseManifest(body []byte) ([]VaultEntry, error) {
+	var entries []VaultEntry
+	ended := false
+	err := parseRecords(body, func(tag byte, value []byte) error {
+		switch tag {
+		case sectionEnd:
+			ended = true
+		case sectionVaultEntry:
+			var e VaultEntry
+			err := parseRecords(value, func(t byte, v []byte) error {
+				switch t {
+				case entryID:
+					e.ID = string(v)
+				case entryName:
+					e.Name = string(v)
+				}
+				return nil
+			})
+			if err != nil || !validVaultID(e.ID) {
+				return errors.New(assets.ErrCorrupted)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil || !ended {
+		return nil, errors.New(assets.ErrCorrupted)
+	}
+	return entries, nil
+}
+
+// Ids become file names, so only hex digits are accepted
+func validVaultID(id string) bool {
+	_, err := hex.DecodeString(id)
+	return err == nil && len(id) == 2*vaultIDSize
+}
+
+// Entries sorted by name
+func VaultEntries() []VaultEntry {
+	entries := slices.Clone(docVault.entries)
+	slices.SortStableFunc(entries, func(a, b VaultEntry) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return entries
+}
+
+func VaultEntryFile(id string) string {
+	return id + "." + assets.FileExtension
+}
+
+func AddVaultEntry(name string) (VaultEntry, error) {
+	var e VaultEntry
+	if err := checkVaultName(name, ""); err != nil {
+		return e, err
+	}
+	b := make([]byte, vaultIDSize)
+	if _, err := rand.Read(b); err != nil {
+		return e, err
+	}
+	e = VaultEntry{ID: hex.EncodeToString(b), Name: strings.TrimSpace(name)}
+	docVault.entries = append(docVault.entries, e)
+	return e, nil
+}
+
+func RenameVaultEntry(id string, name string) error {
+	i := slices.IndexFunc(docVault.entries, func(e VaultEntry) bool { return e.ID == id })
+	if i < 0 {
+		return errors.New(assets.ErrNoSuchEntry)
+	}
+	if err := checkVaultName(name, id); err != nil {
+		return err
+	}
+	docVault.entries[i].Name = strings.TrimSpace(name)
+	return nil
+}
+
+func RemoveVaultEntry(id string) error {
+	i := slices.IndexFunc(docVault.entries, func(e VaultEntry) bool { return e.ID == id })
+	if i < 0 {
+		return errors.New(assets.ErrNoSuchEntry)
+	}
+	docVault.entries = slices.Delete(docVault.entries, i, i+1)
+	return nil
+}
+
+func VaultEntryName(id string) (string, bool) {
+	i := slices.IndexFunc(docVault.entries, func(e VaultEntry) bool { return e.ID == id })
+	if i < 0 {
+		return "", false
+	}
+	return docVault.entries[i].Name, true
+}
+
+func checkVaultName(name string, id string) error {
+	if !docVault.open {
+		return errors.New(assets.ErrNoVault)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New(assets.ErrEntryName)
+	}
+	if slices.ContainsFunc(docVault.entries, func(e VaultEntry) bool { return e.ID != id && strings.EqualFold(e.Name, name) }) {
+		return errors.New(assets.ErrDuplicateEntry)
+	}
+	return nil
+}
+
+// The open document becomes part of the vault, it is opened with the master key from now on
+func JoinVault() error {
+	if !docVault.open {
+		return errors.New(assets.ErrNoVault)
+	}
+	if !session.valid {
+		var key TfKey
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		session = containerSession{key: encode(key), unlocked: -1, valid: true}
+	}
+	wrapKey, checkKey := vaultKeys()
+	wrapped, check := wrapContentKey(decode(session.key), wrapKey, checkKey)
+	session.vaultSlot = append(wrapped[:], check[:]...)
+	return nil
+}
+
+func vaultKeys() (TfKey, TfKey) {
+	var wrapKey, checkKey TfKey
+	master := decode(docVault.session.key)
+	k := HmacSha512(master[:], []byte("vault"))
+	copy(wrapKey[:], k[:TwofishKeysize])
+	copy(checkKey[:], k[TwofishKeysize:])
+	return wrapKey, checkKey
+}
+
+func unwrapWithVault(slot []byte) (TfKey, bool) {
+	var key TfKey
+	if !docVault.open || len(slot) != int(TwofishKeysize)+slotCheckLen {
+		return key, false
+	}
+	var wrapped [TwofishKeysize]byte
+	var check [slotCheckLen]byte
+	copy(wrapped[:], slot[:TwofishKeysize])
+	copy(check[:], slot[TwofishKeysize:])
+	wrapKey, checkKey := vaultKeys()
+	return unwrapContentKey(wrapped, check, wrapKey, checkKey)
+}
+
+// True if the vault opened opens the file without password
+func UnlocksWithVault(payload []byte) bool {
+	if !docVault.open || !isContainerV2(payload) {
+		return false
+	}
+	header, _, err := parseHeader(payload)
+	if err != nil {
+		return false
+	}
+	_, ok := unwrapWithVault(header.vaultSlot)
+	return ok
+}
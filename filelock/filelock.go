@@ -0,0 +1,121 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Advisory lock files to prevent concurrent editing of a document, used by the editor and the command line
+//----------------------------------------------------------------------------------------------------------------------
+
+package filelock
+
+import (
+	"SimpleTwofishEditor/assets"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"syscall"
+	"time"
+)
+
+const lockFilePrefix = ".~lock."
+const lockFileSuffix = "#"
+
+type Info struct {
+	User  string
+	Host  string
+	PID   int
+	Since time.Time
+}
+
+func lockFilePath(p string) string {
+	dir, name := path.Split(p)
+	return path.Join(dir, lockFilePrefix+name+lockFileSuffix)
+}
+
+func ownInfo() Info {
+	info := Info{PID: os.Getpid(), Since: time.Now()}
+	if u, err := user.Current(); err == nil {
+		info.User = u.Username
+	}
+	info.Host, _ = os.Hostname()
+	return info
+}
+
+func (l Info) IsOwn() bool {
+	own := ownInfo()
+	return l.Host == own.Host && l.PID == own.PID
+}
+
+// Left behind on this host by an instance that has ended without removing it, e.g. after a crash
+func (l Info) IsStale() bool {
+	own := ownInfo()
+	return l.Host == own.Host && l.PID != own.PID && !processAlive(l.PID)
+}
+
+// Signal 0 only checks for the process, Windows does not support it but finds running processes only
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return !errors.Is(p.Signal(syscall.Signal(0)), os.ErrProcessDone)
+}
+
+func (l Info) Describe() string {
+	detail := fmt.Sprintf(assets.MsgLockedDetail, l.User, l.Host, l.Since.Format(time.DateTime))
+	if l.IsStale() {
+		detail += "\n" + assets.MsgLockStale
+	}
+	return detail
+}
+
+func readLockFile(fname string) (Info, bool) {
+	var info Info
+	j, err := os.ReadFile(fname)
+	if err != nil {
+		return info, false
+	}
+	_ = json.Unmarshal(j, &info) //damaged lock files are still considered valid
+	return info, true
+}
+
+// Returns the lock holder if the file is locked by another instance
+func LockedByOther(p string) (Info, bool) {
+	info, exists := readLockFile(lockFilePath(p))
+	return info, exists && !info.IsOwn()
+}
+
+// Fails if the file is already locked
+func Acquire(p string) error {
+	fname := lockFilePath(p)
+	j, err := json.Marshal(ownInfo())
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fname, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(j)
+	_ = file.Close()
+	if err != nil {
+		_ = os.Remove(fname)
+		return err
+	}
+	return nil
+}
+
+// The other instance does not remove a lock it no longer owns
+func Break(p string) {
+	_ = os.Remove(lockFilePath(p))
+}
+
+func Release(p string) {
+	fname := lockFilePath(p)
+	if info, exists := readLockFile(fname); exists && info.IsOwn() {
+		_ = os.Remove(fname)
+	}
+}
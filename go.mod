@@ -5,6 +5,9 @@ go 1.23.1
 require (
 	github.com/richardwilkes/toolbox v1.121.0
 	github.com/richardwilkes/unison v0.74.0
+	github.com/yuin/goldmark v1.7.4
+	golang.org/x/crypto v0.27.0
+	golang.org/x/term v0.24.0
 )
 
 require (
@@ -15,7 +18,6 @@ require (
 	github.com/lafriks/go-svg v0.5.1-0.20240818203135-3a6c390fc116 // indirect
 	github.com/pkg/term v1.1.0 // indirect
 	github.com/richardwilkes/json v0.3.0 // indirect
-	github.com/yuin/goldmark v1.7.4 // indirect
 	github.com/zeebo/xxh3 v1.0.2 // indirect
 	golang.org/x/exp v0.0.0-20240909161429-701f63a606c0 // indirect
 	golang.org/x/image v0.20.0 // indirect
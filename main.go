@@ -7,6 +7,7 @@
 package main
 
 import (
+	"SimpleTwofishEditor/cli"
 	"SimpleTwofishEditor/crypto"
 	"SimpleTwofishEditor/ui"
 	"github.com/richardwilkes/unison"
@@ -17,6 +18,9 @@ func main() {
 	if !crypto.SelfTest() {
 		os.Exit(255)
 	}
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
 	unison.Start(
 		unison.StartupFinishedCallback(func() {
 			err := ui.NewMainWindow()
@@ -0,0 +1,234 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Built-in tokenizers: YAML, JSON, .env files and shell scripts
+//----------------------------------------------------------------------------------------------------------------------
+
+package syntax
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type yamlTokenizer struct{}
+type jsonTokenizer struct{}
+type envTokenizer struct{}
+type shellTokenizer struct{}
+
+var yamlKeywords = []string{"true", "false", "yes", "no", "on", "off", "null", "~"}
+var jsonKeywords = []string{"true", "false", "null"}
+var shellKeywords = []string{"if", "then", "else", "elif", "fi", "for", "while", "until", "do", "done", "case", "esac",
+	"in", "function", "select", "return", "exit", "break", "continue", "export", "local", "readonly", "declare",
+	"set", "unset", "source", "alias", "shift", "eval", "exec", "trap"}
+
+func (yamlTokenizer) Name() string { return "YAML" }
+
+func (yamlTokenizer) Tokenize(line []rune) []Token {
+	var tokens []Token
+	i := scanWhile(line, 0, unicode.IsSpace)
+	if s := string(line[i:]); s == "---" || s == "..." {
+		return []Token{{i, len(line), Punctuation}}
+	}
+	for i+1 < len(line) && line[i] == '-' && line[i+1] == ' ' {
+		tokens = append(tokens, Token{i, i + 1, Punctuation})
+		i = scanWhile(line, i+1, unicode.IsSpace)
+	}
+	// key: value, the key may be quoted
+	if end := yamlKeyEnd(line, i); end > i {
+		tokens = append(tokens, Token{i, end, Key}, Token{end, end + 1, Punctuation})
+		i = end + 1
+	}
+	return append(tokens, yamlValue(line, i)...)
+}
+
+func yamlKeyEnd(line []rune, i int) int {
+	if i >= len(line) || line[i] == '#' {
+		return i
+	}
+	j := i
+	if line[j] == '"' || line[j] == '\'' {
+		j = scanQuoted(line, j, line[j] == '"')
+	}
+	for ; j < len(line); j++ {
+		switch {
+		case line[j] == ':' && (j+1 == len(line) || line[j+1] == ' '):
+			return j
+		case line[j] == '#' && line[j-1] == ' ', line[j] == '"' || line[j] == '\'', j == i && strings.ContainsRune("[{&*!|>", line[j]):
+			return i
+		}
+	}
+	return i
+}
+
+func yamlValue(line []rune, i int) []Token {
+	var tokens []Token
+	for i < len(line) {
+		r := line[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#' && (i == 0 || unicode.IsSpace(line[i-1])):
+			return append(tokens, Token{i, len(line), Comment})
+		case r == '"' || r == '\'':
+			end := scanQuoted(line, i, r == '"')
+			tokens = append(tokens, Token{i, end, String})
+			i = end
+		case r == '&' || r == '*' || r == '!':
+			end := scanWhile(line, i, func(r rune) bool { return !unicode.IsSpace(r) })
+			tokens = append(tokens, Token{i, end, Variable})
+			i = end
+		case strings.ContainsRune("[]{},:|>", r):
+			tokens = append(tokens, Token{i, i + 1, Punctuation})
+			i++
+		default:
+			end := scanWhile(line, i, func(r rune) bool { return !unicode.IsSpace(r) && !strings.ContainsRune("[]{},", r) })
+			if word := string(line[i:end]); isNumber(word) {
+				tokens = append(tokens, Token{i, end, Number})
+			} else if slices.Contains(yamlKeywords, strings.ToLower(word)) {
+				tokens = append(tokens, Token{i, end, Keyword})
+			}
+			i = end
+		}
+	}
+	return tokens
+}
+
+func (jsonTokenizer) Name() string { return "JSON" }
+
+func (jsonTokenizer) Tokenize(line []rune) []Token {
+	var tokens []Token
+	for i := 0; i < len(line); {
+		r := line[i]
+		switch {
+		case r == '"':
+			end := scanQuoted(line, i, true)
+			// Strings followed by a colon are keys
+			kind := String
+			if next := scanWhile(line, end, unicode.IsSpace); next < len(line) && line[next] == ':' {
+				kind = Key
+			}
+			tokens = append(tokens, Token{i, end, kind})
+			i = end
+		case strings.ContainsRune("{}[],:", r):
+			tokens = append(tokens, Token{i, i + 1, Punctuation})
+			i++
+		case r == '-' || unicode.IsDigit(r) || unicode.IsLetter(r):
+			end := scanWhile(line, i+1, func(r rune) bool { return isWordRune(r) || strings.ContainsRune(".+-", r) })
+			if word := string(line[i:end]); isNumber(word) {
+				tokens = append(tokens, Token{i, end, Number})
+			} else if slices.Contains(jsonKeywords, word) {
+				tokens = append(tokens, Token{i, end, Keyword})
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func (envTokenizer) Name() string { return ".env" }
+
+func (envTokenizer) Tokenize(line []rune) []Token {
+	var tokens []Token
+	i := scanWhile(line, 0, unicode.IsSpace)
+	if i < len(line) && line[i] == '#' {
+		return []Token{{i, len(line), Comment}}
+	}
+	if strings.HasPrefix(string(line[i:]), "export ") {
+		tokens = append(tokens, Token{i, i + 6, Keyword})
+		i = scanWhile(line, i+6, unicode.IsSpace)
+	}
+	end := scanWhile(line, i, func(r rune) bool { return isWordRune(r) || r == '.' || r == '-' })
+	eq := scanWhile(line, end, unicode.IsSpace)
+	if end == i || eq >= len(line) || line[eq] != '=' {
+		return tokens
+	}
+	tokens = append(tokens, Token{i, end, Key}, Token{eq, eq + 1, Punctuation})
+	for i = eq + 1; i < len(line); {
+		switch r := line[i]; {
+		case r == '\'':
+			end = scanQuoted(line, i, false)
+			tokens = append(tokens, Token{i, end, String})
+			i = end
+		case r == '"':
+			end = scanQuoted(line, i, true)
+			tokens = append(tokens, stringWithVariables(line, i, end)...)
+			i = end
+		case r == '#' && unicode.IsSpace(line[i-1]):
+			return append(tokens, Token{i, len(line), Comment})
+		case r == '$' && scanVariable(line, i) > i:
+			end = scanVariable(line, i)
+			tokens = append(tokens, Token{i, end, Variable})
+			i = end
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+func (shellTokenizer) Name() string { return "Shell" }
+
+func (shellTokenizer) Tokenize(line []rune) []Token {
+	var tokens []Token
+	for i := 0; i < len(line); {
+		r := line[i]
+		switch {
+		case r == '#' && (i == 0 || unicode.IsSpace(line[i-1]) || strings.ContainsRune(";|&(", line[i-1])):
+			return append(tokens, Token{i, len(line), Comment})
+		case r == '\'':
+			end := scanQuoted(line, i, false)
+			tokens = append(tokens, Token{i, end, String})
+			i = end
+		case r == '"':
+			end := scanQuoted(line, i, true)
+			tokens = append(tokens, stringWithVariables(line, i, end)...)
+			i = end
+		case r == '$' && scanVariable(line, i) > i:
+			end := scanVariable(line, i)
+			tokens = append(tokens, Token{i, end, Variable})
+			i = end
+		case strings.ContainsRune("|&;<>(){}[]", r):
+			end := scanWhile(line, i, func(r rune) bool { return strings.ContainsRune("|&;<>", r) })
+			end = max(end, i+1)
+			tokens = append(tokens, Token{i, end, Punctuation})
+			i = end
+		case isWordRune(r) || r == '-':
+			end := scanWhile(line, i+1, func(r rune) bool { return isWordRune(r) || strings.ContainsRune(".-", r) })
+			// Parts of longer words like paths or options are not keywords
+			standalone := (i == 0 || !strings.ContainsRune("/.=-", line[i-1])) && (end == len(line) || !strings.ContainsRune("/=", line[end]))
+			if word := string(line[i:end]); standalone && slices.Contains(shellKeywords, word) {
+				tokens = append(tokens, Token{i, end, Keyword})
+			} else if standalone && isNumber(word) {
+				tokens = append(tokens, Token{i, end, Number})
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return tokens
+}
+
+// Double quoted strings, variables inside are expanded by the shell and shown as such
+func stringWithVariables(line []rune, start int, end int) []Token {
+	var tokens []Token
+	from := start
+	for i := start + 1; i < end; i++ {
+		switch {
+		case line[i] == '\\':
+			i++
+		case line[i] == '$' && scanVariable(line, i) > i:
+			v := min(scanVariable(line, i), end)
+			tokens = append(tokens, Token{from, i, String}, Token{i, v, Variable})
+			from = v
+			i = v - 1
+		}
+	}
+	if from < end {
+		tokens = append(tokens, Token{from, end, String})
+	}
+	return tokens
+}
@@ -0,0 +1,149 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Syntax highlighting: tokenizers split a line into tokens, new languages are added with Register
+//----------------------------------------------------------------------------------------------------------------------
+
+package syntax
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type Kind int
+
+const (
+	Plain Kind = iota
+	Comment
+	String
+	Number
+	Keyword
+	Key
+	Variable
+	Punctuation
+)
+
+// Start and End are rune indexes into the line, End is exclusive
+type Token struct {
+	Start int
+	End   int
+	Kind  Kind
+}
+
+// Lines are tokenized one by one, without state carried from line to line. Text not covered by a token is plain
+type Tokenizer interface {
+	Name() string
+	Tokenize(line []rune) []Token
+}
+
+type language struct {
+	tokenizer Tokenizer
+	aliases   []string
+}
+
+var languages []language
+
+func init() {
+	Register(yamlTokenizer{}, "yml")
+	Register(jsonTokenizer{})
+	Register(envTokenizer{}, "env", "dotenv")
+	Register(shellTokenizer{}, "sh", "bash", "zsh")
+}
+
+// A tokenizer registered later replaces one with the same name
+func Register(t Tokenizer, aliases ...string) {
+	languages = slices.DeleteFunc(languages, func(l language) bool { return strings.EqualFold(l.tokenizer.Name(), t.Name()) })
+	languages = append(languages, language{tokenizer: t, aliases: aliases})
+}
+
+// Names of the languages registered
+func Languages() []string {
+	var names []string
+	for _, l := range languages {
+		names = append(names, l.tokenizer.Name())
+	}
+	return names
+}
+
+// The tokenizer for a name or alias, nil if there is none
+func Lookup(name string) Tokenizer {
+	name = strings.TrimSpace(name)
+	for _, l := range languages {
+		if strings.EqualFold(l.tokenizer.Name(), name) || slices.ContainsFunc(l.aliases, func(a string) bool { return strings.EqualFold(a, name) }) {
+			return l.tokenizer
+		}
+	}
+	return nil
+}
+
+// Index after the closing quote, or the end of the line if the string is not closed
+func scanQuoted(line []rune, i int, escapes bool) int {
+	quote := line[i]
+	for i++; i < len(line); i++ {
+		switch {
+		case escapes && line[i] == '\\':
+			i++
+		case line[i] == quote:
+			return i + 1
+		}
+	}
+	return len(line)
+}
+
+func scanWhile(line []rune, i int, fn func(r rune) bool) int {
+	for i < len(line) && fn(line[i]) {
+		i++
+	}
+	return i
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	digits, dot, exp := false, false, false
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case r == '.' && !dot && !exp:
+			dot = true
+		case (r == 'e' || r == 'E') && digits && !exp && i < len(s)-1:
+			exp = true
+			if s[i+1] == '-' || s[i+1] == '+' {
+				continue
+			}
+		case (r == '-' || r == '+') && exp && (s[i-1] == 'e' || s[i-1] == 'E'):
+		default:
+			return false
+		}
+	}
+	return digits
+}
+
+// $NAME, ${NAME} and $1, returns the index after the variable or i if there is none
+func scanVariable(line []rune, i int) int {
+	if line[i] != '$' || i+1 >= len(line) {
+		return i
+	}
+	switch r := line[i+1]; {
+	case r == '{':
+		for j := i + 2; j < len(line); j++ {
+			if line[j] == '}' {
+				return j + 1
+			}
+		}
+		return len(line)
+	case isWordRune(r):
+		return scanWhile(line, i+1, isWordRune)
+	case strings.ContainsRune("?#@*!$-", r):
+		return i + 2
+	}
+	return i
+}
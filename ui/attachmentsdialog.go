@@ -0,0 +1,166 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Attachments dialog: add, extract and remove the binary files of a document, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"os"
+	"path"
+)
+
+const (
+	responseAddAttachment = unison.ModalResponseUserBase + iota
+	responseExtractAttachment
+	responseRemoveAttachment
+)
+
+var attachmentList *unison.List[string]
+var extractButton *unison.Button
+var removeButton *unison.Button
+
+func ShowAttachmentsDialog() {
+	dialog, err := unison.NewDialog(nil, nil, newAttachmentsPanel(),
+		[]*unison.DialogButtonInfo{
+			{Title: assets.CapAddAttachment, ResponseCode: responseAddAttachment},
+			{Title: assets.CapExtract, ResponseCode: responseExtractAttachment},
+			{Title: assets.CapRemove, ResponseCode: responseRemoveAttachment},
+			unison.NewOKButtonInfoWithTitle(assets.CapClose),
+		},
+		unison.NotResizableWindowOption())
+	if err != nil {
+		panic(err)
+	}
+	wnd := dialog.Window()
+	wnd.SetTitle(assets.CapAttachmentsTitle)
+	if len(titleIcons) > 0 {
+		wnd.SetTitleIcons(titleIcons)
+	}
+	addButton := dialog.Button(responseAddAttachment)
+	addButton.ClickCallback = func() { addAttachment() }
+	addButton.SetEnabled(!isReadOnly)
+	extractButton = dialog.Button(responseExtractAttachment)
+	extractButton.ClickCallback = func() { extractAttachment() }
+	removeButton = dialog.Button(responseRemoveAttachment)
+	removeButton.ClickCallback = func() { removeAttachment() }
+	updateAttachmentList()
+	dialog.RunModal()
+}
+
+func newAttachmentsPanel() *unison.Panel {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  1,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	attachmentList = unison.NewList[string]()
+	attachmentList.SetAllowMultipleSelection(false)
+	attachmentList.NewSelectionCallback = func() { updateAttachmentButtons() }
+	scroller := unison.NewScrollPanel()
+	scroller.SetContent(attachmentList, behavior.Fill, behavior.Fill)
+	scroller.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Width: 360, Height: 140},
+		HAlign:  align.Fill,
+		VAlign:  align.Fill,
+		HGrab:   true,
+		VGrab:   true,
+	})
+	panel.AddChild(scroller)
+	return panel
+}
+
+func updateAttachmentList() {
+	attachmentList.Clear()
+	for _, a := range crypto.Attachments() {
+		attachmentList.Append(fmt.Sprintf(assets.TxtAttachmentEntry, a.Name, formatSize(a.Size)))
+	}
+	attachmentList.MarkForLayoutAndRedraw()
+	updateAttachmentButtons()
+}
+
+func updateAttachmentButtons() {
+	selected := attachmentList.Selection.FirstSet() >= 0
+	extractButton.SetEnabled(selected)
+	removeButton.SetEnabled(selected && !isReadOnly)
+}
+
+func formatSize(size int) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf(assets.TxtBytes, size)
+	case size < 1024*1024:
+		return fmt.Sprintf(assets.TxtKiB, float64(size)/1024)
+	}
+	return fmt.Sprintf(assets.TxtMiB, float64(size)/(1024*1024))
+}
+
+func addAttachment() {
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetCanChooseFiles(true)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	if dialog.RunModal() != true {
+		return
+	}
+	data, ok := readPayload(dialog.Path())
+	if !ok {
+		return
+	}
+	err := crypto.AddAttachment(path.Base(dialog.Path()), data)
+	clear(data)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrAttachment, err)
+		return
+	}
+	isModified = true
+	updateAttachmentList()
+}
+
+// Extracted files are readable by the owner only
+func extractAttachment() {
+	index := attachmentList.Selection.FirstSet()
+	attachments := crypto.Attachments()
+	if index < 0 || index >= len(attachments) {
+		return
+	}
+	dialog := unison.NewSaveDialog()
+	dialog.SetInitialFileName(attachments[index].Name)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	if dialog.RunModal() != true {
+		return
+	}
+	data, err := crypto.AttachmentData(index)
+	if err == nil {
+		err = os.WriteFile(dialog.Path(), data, 0600)
+		clear(data)
+	}
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrFileWrite, err)
+	}
+}
+
+func removeAttachment() {
+	index := attachmentList.Selection.FirstSet()
+	if index < 0 {
+		return
+	}
+	if dialogToConfirm(assets.CapRemove, assets.MsgRemoveAttachment, assets.MsgRemoveAttachmentDetail) != unison.ModalResponseOK {
+		return
+	}
+	if err := crypto.RemoveAttachment(index); err != nil {
+		dialogToDisplaySystemError(assets.ErrAttachment, err)
+		return
+	}
+	isModified = true
+	updateAttachmentList()
+}
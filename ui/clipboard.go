@@ -0,0 +1,64 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Clear secrets from the system clipboard after a timeout, on lock and on quit
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/crypto"
+	"github.com/richardwilkes/unison"
+	"strconv"
+	"time"
+)
+
+const clipboardTimeoutNever = "0"
+const clipboardTimeoutDefault = "30"
+const sensitiveTimeout = 30 * time.Second
+
+var clipboardTimeouts = []string{clipboardTimeoutNever, "10", "20", "30", "60", "120", "300"}
+var clipboardTimeout = clipboardTimeoutDefault
+
+var clipboardHash crypto.ShaResult
+var clipboardOwned = false
+var clipboardSerial = 0
+
+// Remember what we put onto the clipboard (as hash only) and schedule clearing it
+func clipboardCopied(sensitive bool) {
+	text := unison.GlobalClipboard.GetText()
+	if text == "" {
+		return
+	}
+	sha := crypto.NewSha512()
+	clipboardHash = sha.Compute([]byte(text))
+	clipboardOwned = true
+	clipboardSerial++
+	timeout := sensitiveTimeout
+	if seconds, err := strconv.Atoi(clipboardTimeout); err == nil && seconds > 0 {
+		// Sensitive copies never stay longer than sensitiveTimeout
+		if configured := time.Duration(seconds) * time.Second; !sensitive || configured < timeout {
+			timeout = configured
+		}
+	} else if !sensitive {
+		return
+	}
+	serial := clipboardSerial
+	unison.InvokeTaskAfter(func() {
+		if serial == clipboardSerial {
+			clearClipboard()
+		}
+	}, timeout)
+}
+
+// Clear the clipboard, but only if it still contains what we copied
+func clearClipboard() {
+	if !clipboardOwned {
+		return
+	}
+	clipboardOwned = false
+	sha := crypto.NewSha512()
+	if sha.Compute([]byte(unison.GlobalClipboard.GetText())) == clipboardHash {
+		unison.GlobalClipboard.SetText("")
+	}
+	clipboardHash = crypto.ShaResult{}
+}
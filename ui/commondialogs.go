@@ -11,6 +11,7 @@ import (
 	"errors"
 	"github.com/richardwilkes/toolbox/errs"
 	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/check"
 )
 
 func dialogToSaveChanges() int {
@@ -33,6 +34,112 @@ func dialogToSaveChanges() int {
 	return unison.ModalResponseDiscard
 }
 
+func dialogToAskNewPassword() int {
+	msgPanel := unison.NewMessagePanel(assets.MsgUseNewPassword, assets.MsgKeepPassword)
+	if dialog, err := unison.NewDialog(unison.DefaultDialogTheme.QuestionIcon, unison.DefaultDialogTheme.QuestionIconInk, msgPanel,
+		[]*unison.DialogButtonInfo{unison.NewYesButtonInfo(), unison.NewNoButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(assets.CapSaveAs)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		return dialog.RunModal()
+	}
+	return unison.ModalResponseCancel
+}
+
+func dialogToConfirm(title string, primary string, detail string) int {
+	msgPanel := unison.NewMessagePanel(primary, detail)
+	if dialog, err := unison.NewDialog(unison.DefaultDialogTheme.WarningIcon, unison.DefaultDialogTheme.WarningIconInk, msgPanel,
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(title)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		return dialog.RunModal()
+	}
+	return unison.ModalResponseCancel
+}
+
+// Confirmation with a check box, returns the response and the state of the check box
+func dialogToConfirmWithOption(title string, primary string, detail string, option string) (int, bool) {
+	msgPanel := unison.NewMessagePanel(primary, detail)
+	box := unison.NewCheckBox()
+	box.SetTitle(option)
+	box.SetBorder(unison.NewEmptyBorder(unison.Insets{Top: unison.StdVSpacing}))
+	msgPanel.AddChild(box)
+	if dialog, err := unison.NewDialog(unison.DefaultDialogTheme.WarningIcon, unison.DefaultDialogTheme.WarningIconInk, msgPanel,
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(title)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		return dialog.RunModal(), box.State == check.On
+	}
+	return unison.ModalResponseCancel, false
+}
+
+// Ask for a single line of text, returns false if the dialog has been cancelled
+func dialogToEnterText(title string, caption string, text string) (string, bool) {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	lbl := unison.NewLabel()
+	lbl.Font = unison.LabelFont
+	lbl.SetTitle(caption)
+	field := unison.NewField()
+	field.Font = unison.FieldFont
+	field.MinimumTextWidth = inpTextSize
+	field.SetText(text)
+	field.SelectAll()
+	panel.AddChild(lbl)
+	panel.AddChild(field)
+	if dialog, err := unison.NewDialog(nil, nil, panel,
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(title)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		field.RequestFocus()
+		ok := dialog.RunModal() == unison.ModalResponseOK
+		return field.Text(), ok
+	}
+	return "", false
+}
+
+func dialogToDisplayMessage(title string, primary string, detail string) {
+	panel := unison.NewMessagePanel(primary, detail)
+	if dialog, err := unison.NewDialog(nil, nil, panel,
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo()}, unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(title)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		dialog.RunModal()
+	}
+}
+
 func dialogToDisplaySystemError(primary string, detail error) {
 	var msg string
 	var err errs.StackError
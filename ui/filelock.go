@@ -0,0 +1,82 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Lock of the open document and read-only mode, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/filelock"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/unison"
+)
+
+const responseBreakLock = unison.ModalResponseUserBase
+
+// Path of the document whose lock is held
+var heldLock = ""
+var isReadOnly = false
+
+func acquireLock(p string) error {
+	if p == heldLock {
+		return nil
+	}
+	if err := filelock.Acquire(p); err != nil {
+		return err
+	}
+	releaseLock()
+	heldLock = p
+	return nil
+}
+
+func releaseLock() {
+	if heldLock != "" {
+		filelock.Release(heldLock)
+		heldLock = ""
+	}
+}
+
+// Take the lock for the file just opened, fall back to read-only if another instance got there first
+func lockOpenFile(p string, readOnly bool) {
+	if !readOnly {
+		if err := acquireLock(p); err != nil {
+			readOnly = true
+		}
+	} else {
+		releaseLock()
+	}
+	setReadOnly(readOnly)
+}
+
+func setReadOnly(readOnly bool) {
+	isReadOnly = readOnly
+	if readOnly {
+		setLock(true)
+	}
+	lockBtn.SetEnabled(!readOnly)
+	updateWindowTitle()
+}
+
+// Only a lock left behind by an instance no longer running may be broken
+func dialogToReportLock(info filelock.Info) int {
+	msgPanel := unison.NewMessagePanel(assets.MsgFileLocked, info.Describe())
+	buttons := []*unison.DialogButtonInfo{unison.NewOKButtonInfoWithTitle(assets.CapOpenReadOnly)}
+	if info.IsStale() {
+		buttons = append(buttons, &unison.DialogButtonInfo{Title: assets.CapBreakLock, ResponseCode: responseBreakLock})
+	}
+	buttons = append(buttons, unison.NewCancelButtonInfo())
+	if dialog, err := unison.NewDialog(unison.DefaultDialogTheme.WarningIcon, unison.DefaultDialogTheme.WarningIconInk, msgPanel,
+		buttons, unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(assets.CapFileLocked)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		return dialog.RunModal()
+	}
+	return unison.ModalResponseCancel
+}
@@ -0,0 +1,153 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Detect modifications of the open file made by other applications
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"github.com/richardwilkes/toolbox/errs"
+	"github.com/richardwilkes/unison"
+	"os"
+	"path"
+	"slices"
+	"time"
+)
+
+const watchInterval = 2 * time.Second
+
+const (
+	responseReload = unison.ModalResponseUserBase + iota
+	responseSaveCopy
+)
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	hash    crypto.ShaResult
+	valid   bool
+}
+
+var openFileStamp fileStamp
+var watchNotified = false
+var watchRunning = false
+
+func newFileStamp(p string, payload []byte) fileStamp {
+	info, err := os.Stat(p)
+	if err != nil {
+		return fileStamp{}
+	}
+	sha := crypto.NewSha512()
+	return fileStamp{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		hash:    sha.Compute(payload),
+		valid:   true,
+	}
+}
+
+func rememberOpenFile(p string, payload []byte) {
+	openFileStamp = newFileStamp(p, payload)
+	watchNotified = false
+	startWatching()
+}
+
+func forgetOpenFile() {
+	openFileStamp = fileStamp{}
+	watchNotified = false
+}
+
+// A changed time stamp alone (e.g. after touch or a sync tool) is not considered a modification
+func openFileChangedOnDisk() bool {
+	if !openFileStamp.valid || lastOpenFile == "" {
+		return false
+	}
+	p := path.Join(lastOpenFolder, lastOpenFile)
+	info, err := os.Stat(p)
+	if err != nil {
+		return true
+	}
+	if info.ModTime().Equal(openFileStamp.modTime) && info.Size() == openFileStamp.size {
+		return false
+	}
+	payload, err := os.ReadFile(p)
+	if err != nil {
+		return true
+	}
+	sha := crypto.NewSha512()
+	hash := sha.Compute(payload)
+	if slices.Equal(hash[:], openFileStamp.hash[:]) {
+		openFileStamp.modTime = info.ModTime()
+		return false
+	}
+	return true
+}
+
+func startWatching() {
+	if !watchRunning {
+		watchRunning = true
+		unison.InvokeTaskAfter(watchOpenFile, watchInterval)
+	}
+}
+
+func watchOpenFile() {
+	if !openFileStamp.valid {
+		watchRunning = false
+		return
+	}
+	if !watchNotified && unison.ActiveWindow() == mainWindow && openFileChangedOnDisk() {
+		watchNotified = true
+		switch dialogToReportExternalChange(assets.MsgFileChangedOnDisk, false) {
+		case responseReload:
+			// Unsaved changes are only dropped after confirmation
+			fileRevert()
+		case responseSaveCopy:
+			actionSaveAs()
+		}
+	}
+	unison.InvokeTaskAfter(watchOpenFile, watchInterval)
+}
+
+// Returns true if the document may be written to the open file
+func confirmOverwriteExternalChange() bool {
+	if !openFileChangedOnDisk() {
+		return true
+	}
+	switch dialogToReportExternalChange(assets.MsgFileNewerOnDisk, true) {
+	case unison.ModalResponseOK:
+		return true
+	case responseReload:
+		fileRevert()
+	case responseSaveCopy:
+		actionSaveAs()
+	}
+	return false
+}
+
+func dialogToReportExternalChange(primary string, saving bool) int {
+	buttons := []*unison.DialogButtonInfo{
+		{Title: assets.CapReload, ResponseCode: responseReload},
+		{Title: assets.CapSaveCopy, ResponseCode: responseSaveCopy},
+	}
+	if saving {
+		buttons = append(buttons, unison.NewOKButtonInfoWithTitle(assets.CapOverwrite), unison.NewCancelButtonInfo())
+	} else {
+		buttons = append(buttons, unison.NewCancelButtonInfo())
+		buttons[len(buttons)-1].Title = assets.CapIgnore
+	}
+	msgPanel := unison.NewMessagePanel(primary, assets.MsgExternalChangeDetail)
+	if dialog, err := unison.NewDialog(unison.DefaultDialogTheme.WarningIcon, unison.DefaultDialogTheme.WarningIconInk, msgPanel,
+		buttons, unison.NotResizableWindowOption()); err != nil {
+		errs.Log(err)
+	} else {
+		wnd := dialog.Window()
+		wnd.SetTitle(assets.CapFileChanged)
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		return dialog.RunModal()
+	}
+	return unison.ModalResponseCancel
+}
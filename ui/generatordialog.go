@@ -0,0 +1,221 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Password generator dialog, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/check"
+)
+
+const (
+	GenPassword = iota
+	GenPassphrase
+)
+
+const genResultSize = 360
+
+var genLengths = []int{8, 10, 12, 14, 16, 20, 24, 32, 40, 48, 64}
+var genWordCounts = []int{3, 4, 5, 6, 7, 8, 10, 12}
+var genSeparators = []string{"-", ".", "_", " "}
+
+var (
+	genDialog       *unison.Dialog
+	genKindMenu     *unison.PopupMenu[string]
+	genLengthMenu   *unison.PopupMenu[int]
+	genSepMenu      *unison.PopupMenu[string]
+	genLowerBox     *unison.CheckBox
+	genUpperBox     *unison.CheckBox
+	genDigitsBox    *unison.CheckBox
+	genSymbolsBox   *unison.CheckBox
+	genResult       *unison.Field
+	genEntropyLabel *unison.Label
+	genUseButton    *unison.Button
+	genCanUse       bool
+)
+
+// Returns the generated password, or an empty string if the dialog has been cancelled
+func ShowGeneratorDialog(useTitle string, canUse bool) string {
+	var err error
+	genDialog, err = unison.NewDialog(nil, nil, newGeneratorPanel(),
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfoWithTitle(useTitle), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption())
+	if err != nil {
+		panic(err)
+	}
+	wnd := genDialog.Window()
+	wnd.SetTitle(assets.CapGenerator)
+	if len(titleIcons) > 0 {
+		wnd.SetTitleIcons(titleIcons)
+	}
+	genUseButton = genDialog.Button(unison.ModalResponseOK)
+	genCanUse = canUse
+	generate()
+	result := ""
+	if genDialog.RunModal() == unison.ModalResponseOK {
+		result = genResult.Text()
+	}
+	storeGeneratorSettings()
+	return result
+}
+
+func newGeneratorPanel() *unison.Panel {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	genKindMenu = unison.NewPopupMenu[string]()
+	genKindMenu.AddItem(assets.TxtGenKinds[:]...)
+	genKindMenu.SelectIndex(genSettings.Kind)
+	addPreferencesRow(panel, assets.CapGenKind, genKindMenu.AsPanel())
+	genLengthMenu = unison.NewPopupMenu[int]()
+	addPreferencesRow(panel, assets.CapGenLength, genLengthMenu.AsPanel())
+	genSepMenu = unison.NewPopupMenu[string]()
+	genSepMenu.AddItem(genSeparators...)
+	genSepMenu.Select(genSettings.Separator)
+	addPreferencesRow(panel, assets.CapGenSeparator, genSepMenu.AsPanel())
+	genLowerBox = newGeneratorCheckBox(panel, assets.CapGenLower, genSettings.Classes&crypto.ClassLower != 0)
+	genUpperBox = newGeneratorCheckBox(panel, assets.CapGenUpper, genSettings.Classes&crypto.ClassUpper != 0)
+	genDigitsBox = newGeneratorCheckBox(panel, assets.CapGenDigits, genSettings.Classes&crypto.ClassDigit != 0)
+	genSymbolsBox = newGeneratorCheckBox(panel, assets.CapGenSymbols, genSettings.Classes&crypto.ClassSymbol != 0)
+	genResult = unison.NewField()
+	genResult.Font = unison.FieldFont
+	genResult.MinimumTextWidth = genResultSize
+	genResult.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  2,
+		VSpan:  1,
+		HAlign: align.Fill,
+		HGrab:  true,
+	})
+	panel.AddChild(genResult)
+	genEntropyLabel = unison.NewLabel()
+	genEntropyLabel.Font = unison.LabelFont
+	genEntropyLabel.SetTitle(" ")
+	panel.AddChild(genEntropyLabel)
+	genButton := unison.NewButton()
+	genButton.SetTitle(assets.CapGenerate)
+	genButton.SetLayoutData(&unison.FlexLayoutData{HAlign: align.End})
+	genButton.ClickCallback = func() { generate() }
+	panel.AddChild(genButton)
+	genKindMenu.SelectionChangedCallback = func(_ *unison.PopupMenu[string]) {
+		fillLengthMenu()
+		generate()
+	}
+	genLengthMenu.SelectionChangedCallback = func(_ *unison.PopupMenu[int]) { generate() }
+	genSepMenu.SelectionChangedCallback = func(_ *unison.PopupMenu[string]) { generate() }
+	fillLengthMenu()
+	panel.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  1,
+		VSpan:  1,
+		VAlign: align.Middle,
+	})
+	return panel
+}
+
+func newGeneratorCheckBox(panel *unison.Panel, title string, on bool) *unison.CheckBox {
+	box := unison.NewCheckBox()
+	box.SetTitle(title)
+	box.State = check.FromBool(on)
+	box.ClickCallback = func() { generate() }
+	box.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  2,
+		VSpan:  1,
+		HAlign: align.Start,
+	})
+	panel.AddChild(box)
+	return box
+}
+
+func fillLengthMenu() {
+	genLengthMenu.RemoveAllItems()
+	passphrase := genKindMenu.SelectedIndex() == GenPassphrase
+	if passphrase {
+		genLengthMenu.AddItem(genWordCounts...)
+		genLengthMenu.Select(genSettings.Words)
+	} else {
+		genLengthMenu.AddItem(genLengths...)
+		genLengthMenu.Select(genSettings.Length)
+	}
+	if genLengthMenu.SelectedIndex() < 0 {
+		genLengthMenu.SelectIndex(0)
+	}
+	genSepMenu.SetEnabled(passphrase)
+	for _, box := range []*unison.CheckBox{genLowerBox, genUpperBox, genDigitsBox, genSymbolsBox} {
+		box.SetEnabled(!passphrase)
+	}
+}
+
+func generatorAlphabet() string {
+	alphabet := ""
+	if genLowerBox.State == check.On {
+		alphabet += crypto.AlphabetLower
+	}
+	if genUpperBox.State == check.On {
+		alphabet += crypto.AlphabetUpper
+	}
+	if genDigitsBox.State == check.On {
+		alphabet += crypto.AlphabetDigits
+	}
+	if genSymbolsBox.State == check.On {
+		alphabet += crypto.AlphabetSymbols
+	}
+	return alphabet
+}
+
+func generate() {
+	var result string
+	var entropy float64
+	var err error
+	n, _ := genLengthMenu.Selected()
+	if genKindMenu.SelectedIndex() == GenPassphrase {
+		sep, _ := genSepMenu.Selected()
+		result, err = crypto.GeneratePassphrase(n, sep)
+		entropy = crypto.PassphraseEntropy(n)
+	} else {
+		alphabet := generatorAlphabet()
+		result, err = crypto.GeneratePassword(n, alphabet)
+		entropy = crypto.PasswordEntropy(n, alphabet)
+	}
+	if err != nil {
+		result = ""
+		genEntropyLabel.SetTitle(err.Error())
+	} else {
+		genEntropyLabel.SetTitle(fmt.Sprintf(assets.TxtGenEntropy, entropy))
+	}
+	genResult.SetText(result)
+	genUseButton.SetEnabled(genCanUse && result != "")
+	genDialog.Window().Content().MarkForLayoutAndRedraw()
+}
+
+func storeGeneratorSettings() {
+	genSettings.Kind = genKindMenu.SelectedIndex()
+	if n, ok := genLengthMenu.Selected(); ok {
+		if genSettings.Kind == GenPassphrase {
+			genSettings.Words = n
+		} else {
+			genSettings.Length = n
+		}
+	}
+	if sep, ok := genSepMenu.Selected(); ok {
+		genSettings.Separator = sep
+	}
+	genSettings.Classes = 0
+	for _, c := range []struct {
+		box   *unison.CheckBox
+		class int
+	}{{genLowerBox, crypto.ClassLower}, {genUpperBox, crypto.ClassUpper},
+		{genDigitsBox, crypto.ClassDigit}, {genSymbolsBox, crypto.ClassSymbol}} {
+		if c.box.State == check.On {
+			genSettings.Classes |= c.class
+		}
+	}
+}
@@ -0,0 +1,155 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Syntax highlighting of the editor, the language is stored in the document properties,
+// using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/syntax"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/check"
+	"github.com/richardwilkes/unison/enums/paintstyle"
+	"github.com/richardwilkes/unison/enums/pathop"
+)
+
+var highlighter syntax.Tokenizer
+
+var syntaxColors = map[syntax.Kind]unison.Ink{
+	syntax.Comment:     &unison.ThemeColor{Light: unison.RGB(106, 115, 125), Dark: unison.RGB(139, 148, 158)},
+	syntax.String:      &unison.ThemeColor{Light: unison.RGB(3, 47, 98), Dark: unison.RGB(165, 214, 255)},
+	syntax.Number:      &unison.ThemeColor{Light: unison.RGB(0, 92, 197), Dark: unison.RGB(121, 192, 255)},
+	syntax.Keyword:     &unison.ThemeColor{Light: unison.RGB(215, 58, 73), Dark: unison.RGB(255, 123, 114)},
+	syntax.Key:         &unison.ThemeColor{Light: unison.RGB(34, 134, 58), Dark: unison.RGB(126, 231, 135)},
+	syntax.Variable:    &unison.ThemeColor{Light: unison.RGB(227, 98, 9), Dark: unison.RGB(255, 166, 87)},
+	syntax.Punctuation: &unison.ThemeColor{Light: unison.RGB(88, 96, 105), Dark: unison.RGB(201, 209, 217)},
+}
+
+// One action for "None", followed by one for each language registered
+func newSyntaxActions() []*unison.Action {
+	var actions []*unison.Action
+	for i, name := range append([]string{""}, syntax.Languages()...) {
+		t := syntax.Lookup(name)
+		title := name
+		if t == nil {
+			title = assets.CapSyntaxNone
+		}
+		actions = append(actions, &unison.Action{
+			ID:    EditSyntaxActionID + i,
+			Title: title,
+			EnabledCallback: func(_ *unison.Action, src any) bool {
+				if item, ok := src.(unison.MenuItem); ok {
+					item.SetCheckState(check.FromBool(highlighter == t))
+				}
+				return true
+			},
+			ExecuteCallback: func(_ *unison.Action, _ any) {
+				setSyntax(t)
+			},
+		})
+	}
+	return actions
+}
+
+// The language is taken from the document properties
+func updateSyntax() {
+	name, _ := documentProperty(assets.SyntaxProperty)
+	highlighter = syntax.Lookup(name)
+	textEditor.MarkForRedraw()
+}
+
+// Read-only documents are highlighted, the choice is not stored
+func setSyntax(t syntax.Tokenizer) {
+	if isReadOnly {
+		highlighter = t
+		textEditor.MarkForRedraw()
+		return
+	}
+	name := ""
+	if t != nil {
+		name = t.Name()
+	}
+	setDocumentProperty(assets.SyntaxProperty, name)
+	updateSyntax()
+}
+
+// The tokens of the lines visible are drawn over the text in their colors
+func drawSyntax(gc *unison.Canvas, dirty unison.Rect) {
+	if highlighter == nil || textEditor.ObscurementRune != 0 {
+		return
+	}
+	runes := []rune(textEditor.Text())
+	if len(runes) == 0 {
+		return
+	}
+	gc.Save()
+	defer gc.Restore()
+	gc.ClipRect(textEditor.ContentRect(false), pathop.Intersect, false)
+	first := textEditor.ToSelectionIndex(dirty.Point)
+	last := textEditor.ToSelectionIndex(unison.Point{X: dirty.Right(), Y: dirty.Bottom()})
+	start := first
+	for start > 0 && runes[start-1] != '\n' {
+		start--
+	}
+	for start <= last && start < len(runes) {
+		end := start
+		for end < len(runes) && runes[end] != '\n' {
+			end++
+		}
+		for _, t := range highlighter.Tokenize(runes[start:end]) {
+			if ink, ok := syntaxColors[t.Kind]; ok {
+				highlightRange(gc, runes, start+t.Start, start+t.End, ink)
+			}
+		}
+		start = end + 1
+	}
+}
+
+func highlightRange(gc *unison.Canvas, runes []rune, start int, end int, ink unison.Ink) {
+	if start >= end {
+		return
+	}
+	if textEditor.Enabled() && textEditor.Focused() {
+		from, to := textEditor.Selection()
+		switch {
+		case from < to && from < end && to > start:
+			// The selection is drawn by the field
+			highlightRange(gc, runes, start, from, ink)
+			highlightRange(gc, runes, to, end, ink)
+			return
+		case from == to && from > start && from < end:
+			// Split at the cursor, it stays visible between both parts
+			highlightRange(gc, runes, start, from, ink)
+			highlightRange(gc, runes, from, end, ink)
+			return
+		}
+	}
+	// Lines wrapped are drawn one by one
+	pos := textEditor.FromSelectionIndex(start)
+	split := end
+	if textEditor.FromSelectionIndex(end-1).Y != pos.Y {
+		lo, hi := start+1, end-1
+		for lo < hi {
+			mid := (lo + hi) / 2
+			if textEditor.FromSelectionIndex(mid).Y != pos.Y {
+				hi = mid
+			} else {
+				lo = mid + 1
+			}
+		}
+		split = lo
+	}
+	bg := textEditor.EditableInk
+	if !textEditor.Enabled() {
+		bg = textEditor.BackgroundInk
+	}
+	text := unison.NewTextFromRunes(runes[start:split], &unison.TextDecoration{Font: textEditor.Font, OnBackgroundInk: ink})
+	// Half a pixel inset, a cursor at either end is not painted over
+	rect := unison.NewRect(pos.X+0.5, pos.Y, text.Width()-1, max(text.Height(), textEditor.Font.LineHeight()))
+	gc.DrawRect(rect, bg.Paint(gc, rect, paintstyle.Fill))
+	text.Draw(gc, pos.X, pos.Y+text.Baseline())
+	highlightRange(gc, runes, split, end, ink)
+}
@@ -0,0 +1,120 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Create and load the identity (X25519 key pair) used to open documents shared with public keys
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"github.com/richardwilkes/unison"
+	"os"
+	"path"
+)
+
+var identityFile = ""
+
+func toolsNewIdentity() {
+	if crypto.IdentityLoaded() {
+		if dialogToConfirm(assets.CapNewIdentity, assets.MsgReplaceIdentity, assets.MsgReplaceIdentityDetail) != unison.ModalResponseOK {
+			return
+		}
+	}
+	if ShowPasswordDialog(PwdIdentitySet) != unison.ModalResponseOK {
+		return
+	}
+	dialog := unison.NewSaveDialog()
+	dialog.SetInitialFileName(assets.IdentityFileName)
+	dialog.SetInitialDirectory(path.Dir(identityFile))
+	dialog.SetAllowedExtensions(assets.IdentityExtension)
+	if dialog.RunModal() != true {
+		return
+	}
+	p := dialog.Path()
+	publicKey, err := crypto.GenerateIdentity()
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrEncryptionError, err)
+		return
+	}
+	data, err := crypto.EncryptIdentity(enteredPassphrase)
+	clear(enteredPassphrase)
+	if err != nil {
+		crypto.ForgetIdentity()
+		dialogToDisplaySystemError(assets.ErrEncryptionError, err)
+		return
+	}
+	if err = os.WriteFile(p, data, 0600); err != nil {
+		crypto.ForgetIdentity()
+		dialogToDisplaySystemError(assets.ErrFileWrite, err)
+		return
+	}
+	identityFile = p
+	unison.GlobalClipboard.SetText(publicKey)
+	clipboardCopied(false)
+	dialogToDisplayMessage(assets.CapNewIdentity, assets.MsgIdentityCreated, publicKey)
+}
+
+func toolsLoadIdentity() {
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetCanChooseFiles(true)
+	if identityFile != "" {
+		dialog.SetInitialDirectory(path.Dir(identityFile))
+	}
+	dialog.SetAllowedExtensions(assets.IdentityExtension)
+	if dialog.RunModal() == true {
+		loadIdentityFile(dialog.Path())
+	}
+}
+
+func toolsCopyPublicKey() {
+	if publicKey := crypto.IdentityPublicKey(); publicKey != "" {
+		unison.GlobalClipboard.SetText(publicKey)
+		clipboardCopied(false)
+	}
+}
+
+// Ask for the passphrase and load the identity, returns true on success
+func loadIdentityFile(p string) bool {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrFileRead, err)
+		return false
+	}
+	if ShowPasswordDialog(PwdIdentityGet) != unison.ModalResponseOK {
+		return false
+	}
+	message := crypto.LoadIdentity(data, enteredPassphrase)
+	clear(enteredPassphrase)
+	if message != "" {
+		dialogToDisplayErrorMessage(assets.ErrDecryptionError, message)
+		return false
+	}
+	identityFile = p
+	return true
+}
+
+// Files shared with public keys only: load the identity (remembered from last time) before opening
+func ensureIdentity(payload []byte) bool {
+	if crypto.UnlocksWithIdentity(payload) {
+		return true
+	}
+	if identityFile != "" {
+		if _, err := os.Stat(identityFile); errors.Is(err, os.ErrNotExist) {
+			identityFile = ""
+		}
+	}
+	if identityFile == "" || crypto.IdentityLoaded() {
+		toolsLoadIdentity()
+	} else {
+		loadIdentityFile(identityFile)
+	}
+	if !crypto.UnlocksWithIdentity(payload) {
+		dialogToDisplayErrorMessage(assets.ErrDecryptionError, assets.ErrIdentityRequired)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Import and export of foreign file formats, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"os"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var importExtensions = append([]string{assets.AgeExtension}, strings.Split(assets.PgpExtensions, ",")...)
+
+// Plain text file to be securely deleted once the imported document has been saved
+var plainTextSource = ""
+
+func fileImport(plain bool) {
+	answer := dialogToSaveChanges()
+	if answer == unison.ModalResponseCancel {
+		return
+	}
+	if answer == unison.ModalResponseOK {
+		if !actionSave() {
+			return
+		}
+	}
+	if plain {
+		actionImportPlain()
+	} else {
+		actionImport()
+	}
+}
+
+// The imported text becomes a new, unsaved document
+func actionImport() {
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetCanChooseFiles(true)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	dialog.SetAllowedExtensions(importExtensions...)
+	if dialog.RunModal() != true {
+		return
+	}
+	payload, ok := readPayload(dialog.Path())
+	if !ok {
+		return
+	}
+	if crypto.ImportFormat(payload) == 0 {
+		dialogToDisplayErrorMessage(assets.ErrFileRead, assets.ErrUnknownFormat)
+		return
+	}
+	var passphrase []byte
+	if !crypto.ImportUnlocksWithIdentity(payload) {
+		if ShowPasswordDialog(PwdImport) != unison.ModalResponseOK {
+			return
+		}
+		passphrase = enteredPassphrase
+	}
+	text, message := crypto.ImportPayload(payload, passphrase)
+	clear(enteredPassphrase)
+	if message != "" {
+		dialogToDisplayErrorMessage(assets.ErrDecryptionError, message)
+		return
+	}
+	actionNew()
+	lastOpenFolder, _ = path.Split(dialog.Path())
+	textEditor.SetText(text)
+	textEditor.SetSelectionToStart()
+	isModified = true
+}
+
+// Neither the folder of the plain text file nor the file itself are remembered
+func actionImportPlain() {
+	detail := assets.MsgImportPlainDetail + "\n\n" + fmt.Sprintf(assets.MsgShredNotice, crypto.ShredPasses)
+	answer, shred := dialogToConfirmWithOption(assets.CapImportPlain, assets.MsgImportPlain, detail, assets.CapShredSource)
+	if answer != unison.ModalResponseOK {
+		return
+	}
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetCanChooseFiles(true)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	if dialog.RunModal() != true {
+		return
+	}
+	payload, ok := readPayload(dialog.Path())
+	if !ok {
+		return
+	}
+	if !utf8.Valid(payload) {
+		dialogToDisplayErrorMessage(assets.ErrFileRead, assets.ErrNoPlainText)
+		return
+	}
+	actionNew()
+	textEditor.SetText(string(payload))
+	clear(payload)
+	textEditor.SetSelectionToStart()
+	isModified = true
+	if shred {
+		plainTextSource = dialog.Path()
+	}
+}
+
+// Called after the document has been written encrypted
+func shredPlainTextSource() {
+	if plainTextSource == "" {
+		return
+	}
+	p := plainTextSource
+	plainTextSource = ""
+	if err := crypto.ShredFile(p, crypto.ShredPasses); err != nil {
+		dialogToDisplaySystemError(assets.ErrShredFile, err)
+	}
+}
+
+func fileExportPlain() {
+	if dialogToConfirm(assets.CapExportPlain, assets.MsgExportPlain, assets.MsgExportPlainDetail) != unison.ModalResponseOK {
+		return
+	}
+	dialog := unison.NewSaveDialog()
+	name := assets.UnnamedFileNoExt
+	if lastOpenFile != "" {
+		name = strings.TrimSuffix(lastOpenFile, path.Ext(lastOpenFile))
+	}
+	dialog.SetInitialFileName(name + "." + assets.PlainExtension)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	dialog.SetAllowedExtensions(assets.PlainExtension)
+	if dialog.RunModal() != true {
+		return
+	}
+	if err := os.WriteFile(dialog.Path(), []byte(textEditor.Text()), 0600); err != nil {
+		dialogToDisplaySystemError(assets.ErrFileWrite, err)
+	}
+}
+
+func fileExport(format int, extension string) {
+	if ShowPasswordDialog(PwdExport) != unison.ModalResponseOK {
+		return
+	}
+	defer clear(enteredPassphrase)
+	dialog := unison.NewSaveDialog()
+	name := assets.UnnamedFileNoExt
+	if lastOpenFile != "" {
+		name = strings.TrimSuffix(lastOpenFile, path.Ext(lastOpenFile))
+	}
+	dialog.SetInitialFileName(name + "." + extension)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	dialog.SetAllowedExtensions(extension)
+	if dialog.RunModal() != true {
+		return
+	}
+	data, err := crypto.ExportPayload([]byte(textEditor.Text()), format, enteredPassphrase)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrEncryptionError, err)
+		return
+	}
+	if err = os.WriteFile(dialog.Path(), data, 0644); err != nil {
+		dialogToDisplaySystemError(assets.ErrFileWrite, err)
+	}
+}
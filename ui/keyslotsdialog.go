@@ -0,0 +1,227 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Key slots dialog: list, add and revoke the passwords and recipients of a document, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"os"
+	"path"
+)
+
+const (
+	responseAddSlot = unison.ModalResponseUserBase + iota
+	responseAddRecipient
+	responseRevokeSlot
+)
+
+var slotList *unison.List[string]
+var revokeButton *unison.Button
+var recipientOkButton *unison.Button
+var recipientLabelField *unison.Field
+var recipientKeyField *unison.Field
+
+func ShowKeySlotsDialog() {
+	if !crypto.HasDocumentKey() {
+		if ShowPasswordDialog(PwdSet) != unison.ModalResponseOK {
+			return
+		}
+	}
+	dialog, err := unison.NewDialog(nil, nil, newKeySlotsPanel(),
+		[]*unison.DialogButtonInfo{
+			{Title: assets.CapAddPassword, ResponseCode: responseAddSlot},
+			{Title: assets.CapAddRecipient, ResponseCode: responseAddRecipient},
+			{Title: assets.CapRevoke, ResponseCode: responseRevokeSlot},
+			unison.NewOKButtonInfoWithTitle(assets.CapClose),
+		},
+		unison.NotResizableWindowOption())
+	if err != nil {
+		panic(err)
+	}
+	wnd := dialog.Window()
+	wnd.SetTitle(assets.CapKeySlotsTitle)
+	if len(titleIcons) > 0 {
+		wnd.SetTitleIcons(titleIcons)
+	}
+	dialog.Button(responseAddSlot).ClickCallback = func() {
+		if ShowPasswordDialog(PwdAdd) == unison.ModalResponseOK {
+			keySlotsChanged()
+		}
+	}
+	dialog.Button(responseAddRecipient).ClickCallback = func() {
+		if showRecipientDialog() {
+			keySlotsChanged()
+		}
+	}
+	revokeButton = dialog.Button(responseRevokeSlot)
+	revokeButton.ClickCallback = func() { revokeKeySlot() }
+	updateKeySlotList()
+	dialog.RunModal()
+}
+
+func newKeySlotsPanel() *unison.Panel {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  1,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	slotList = unison.NewList[string]()
+	slotList.SetAllowMultipleSelection(false)
+	slotList.NewSelectionCallback = func() { updateRevokeButton() }
+	scroller := unison.NewScrollPanel()
+	scroller.SetContent(slotList, behavior.Fill, behavior.Fill)
+	scroller.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Width: 360, Height: 140},
+		HAlign:  align.Fill,
+		VAlign:  align.Fill,
+		HGrab:   true,
+		VGrab:   true,
+	})
+	panel.AddChild(scroller)
+	// Slots are stored in the header of the file, it is rewritten without decrypting the text
+	hint := unison.NewLabel()
+	hint.Font = unison.LabelFont
+	hint.SetTitle(assets.TxtSlotLabelHint)
+	panel.AddChild(hint)
+	return panel
+}
+
+func updateKeySlotList() {
+	slotList.Clear()
+	for _, s := range crypto.KeySlots() {
+		label := s.Label
+		if label == "" {
+			label = fmt.Sprintf(assets.TxtSlotUnnamed, s.Index+1)
+		}
+		var entry string
+		if s.Recipient != "" {
+			entry = fmt.Sprintf(assets.TxtRecipientEntry, label, shortRecipient(s.Recipient))
+		} else {
+			entry = fmt.Sprintf(assets.TxtSlotEntry, label, assets.TxtFactors[s.Factors-1])
+		}
+		if s.Unlocked {
+			entry += assets.TxtSlotCurrent
+		}
+		slotList.Append(entry)
+	}
+	slotList.MarkForLayoutAndRedraw()
+	updateRevokeButton()
+}
+
+func shortRecipient(s string) string {
+	if len(s) <= 20 {
+		return s
+	}
+	return s[:12] + "..." + s[len(s)-6:]
+}
+
+func updateRevokeButton() {
+	revokeButton.SetEnabled(slotList.Count() > 1 && slotList.Selection.FirstSet() >= 0)
+}
+
+func revokeKeySlot() {
+	index := slotList.Selection.FirstSet()
+	slots := crypto.KeySlots()
+	if index < 0 || index >= len(slots) {
+		return
+	}
+	detail := assets.MsgRevokeSlotDetail
+	if slots[index].Unlocked {
+		detail = assets.MsgRevokeCurrentDetail
+	}
+	if dialogToConfirm(assets.CapRevoke, assets.MsgRevokeSlot, detail) != unison.ModalResponseOK {
+		return
+	}
+	if err := crypto.RevokeKeySlot(index); err != nil {
+		dialogToDisplaySystemError(assets.ErrKeySlotsUpdate, err)
+		return
+	}
+	keySlotsChanged()
+}
+
+func showRecipientDialog() bool {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	lblLabel := unison.NewLabel()
+	lblLabel.Font = unison.LabelFont
+	lblLabel.SetTitle(assets.CapSlotLabel)
+	recipientLabelField = unison.NewField()
+	recipientLabelField.Font = unison.FieldFont
+	recipientLabelField.MinimumTextWidth = inpTextSize
+	lblKey := unison.NewLabel()
+	lblKey.Font = unison.LabelFont
+	lblKey.SetTitle(assets.CapPublicKey)
+	recipientKeyField = unison.NewField()
+	recipientKeyField.Font = unison.FieldFont
+	recipientKeyField.MinimumTextWidth = 2 * inpTextSize
+	recipientKeyField.ModifiedCallback = func(_, after *unison.FieldState) {
+		_, err := crypto.ParseRecipient(after.Text)
+		recipientOkButton.SetEnabled(err == nil)
+	}
+	panel.AddChild(lblLabel)
+	panel.AddChild(recipientLabelField)
+	panel.AddChild(lblKey)
+	panel.AddChild(recipientKeyField)
+	dialog, err := unison.NewDialog(nil, nil, panel,
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption())
+	if err != nil {
+		panic(err)
+	}
+	wnd := dialog.Window()
+	wnd.SetTitle(assets.CapRecipientAdd)
+	if len(titleIcons) > 0 {
+		wnd.SetTitleIcons(titleIcons)
+	}
+	recipientOkButton = dialog.Button(unison.ModalResponseOK)
+	recipientOkButton.ClickCallback = func() {
+		if err := crypto.AddRecipient(recipientLabelField.Text(), recipientKeyField.Text()); err != nil {
+			dialogToDisplaySystemError(assets.ErrKeySlotsUpdate, err)
+			return
+		}
+		dialog.StopModal(unison.ModalResponseOK)
+	}
+	recipientOkButton.SetEnabled(false)
+	return dialog.RunModal() == unison.ModalResponseOK
+}
+
+// A saved, unmodified document gets its header rewritten right away, otherwise the change is saved with the text
+func keySlotsChanged() {
+	updateKeySlotList()
+	if lastOpenFile == "" || isModified || isReadOnly || openFileChangedOnDisk() {
+		isModified = true
+		return
+	}
+	p := path.Join(lastOpenFolder, lastOpenFile)
+	payload, err := os.ReadFile(p)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrFileRead, err)
+		isModified = true
+		return
+	}
+	payload, message := crypto.RewriteKeySlots(payload)
+	if message != "" {
+		// e.g. a v1 file, which has no key slots
+		isModified = true
+		return
+	}
+	if err = os.WriteFile(p, payload, 0644); err != nil {
+		dialogToDisplaySystemError(assets.ErrFileWrite, err)
+		isModified = true
+		return
+	}
+	rememberOpenFile(p, payload)
+}
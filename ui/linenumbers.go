@@ -0,0 +1,121 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Line numbers beside the editor and the current line, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/paintstyle"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const gutterMargin = 6
+
+var showLineNumbers = false
+var lineNumbers *unison.Panel
+var lineNumberDigits = 0
+
+var currentLineInk = &unison.ThemeColor{
+	Light: unison.RGB(0, 0, 0).SetAlphaIntensity(0.05),
+	Dark:  unison.RGB(255, 255, 255).SetAlphaIntensity(0.06),
+}
+
+// The gutter is the row header of the scroller, it scrolls together with the editor
+func createLineNumbers() {
+	lineNumbers = unison.NewPanel()
+	lineNumbers.SetSizer(func(_ unison.Size) (minSize, prefSize, maxSize unison.Size) {
+		width := textEditor.Font.SimpleWidth(strings.Repeat("0", max(lineNumberDigits, 2))) + 2*gutterMargin
+		prefSize = unison.Size{Width: width}
+		return prefSize, prefSize, prefSize
+	})
+	lineNumbers.DrawCallback = drawLineNumbers
+}
+
+func editLineNumbers() {
+	setLineNumbers(!showLineNumbers)
+}
+
+func setLineNumbers(show bool) {
+	showLineNumbers = show
+	if show {
+		editorScroller.SetRowHeader(lineNumbers)
+		updateLineNumbers()
+	} else {
+		editorScroller.SetRowHeader(nil)
+	}
+}
+
+// The gutter gets wider when the number of lines needs another digit
+func updateLineNumbers() {
+	if !showLineNumbers {
+		return
+	}
+	if digits := len(strconv.Itoa(strings.Count(textEditor.Text(), "\n") + 1)); digits != lineNumberDigits {
+		lineNumberDigits = digits
+		editorPanel.MarkForLayoutAndRedraw()
+	}
+	lineNumbers.MarkForRedraw()
+}
+
+// Rune index of the start of the line at index
+func lineStart(runes []rune, index int) int {
+	index = min(index, len(runes))
+	for index > 0 && runes[index-1] != '\n' {
+		index--
+	}
+	return index
+}
+
+// Rune index of the line feed ending the line at index, or the end of the text
+func lineEnd(runes []rune, index int) int {
+	if i := slices.Index(runes[index:], '\n'); i >= 0 {
+		return index + i
+	}
+	return len(runes)
+}
+
+// The gutter and the editor share their vertical coordinates
+func drawLineNumbers(gc *unison.Canvas, dirty unison.Rect) {
+	rect := lineNumbers.ContentRect(true)
+	gc.DrawRect(rect, textEditor.BackgroundInk.Paint(gc, rect, paintstyle.Fill))
+	runes := []rune(textEditor.Text())
+	cursor, _ := textEditor.Selection()
+	current := lineStart(runes, cursor)
+	dimmed := &unison.ColorFilteredInk{OriginalInk: textEditor.OnBackgroundInk, ColorFilter: unison.Alpha50Filter()}
+	start := lineStart(runes, textEditor.ToSelectionIndex(unison.Point{Y: dirty.Y}))
+	last := textEditor.ToSelectionIndex(unison.Point{X: textEditor.ContentRect(false).Right(), Y: dirty.Bottom()})
+	number := strings.Count(string(runes[:start]), "\n") + 1
+	for {
+		var ink unison.Ink = dimmed
+		if start == current {
+			ink = textEditor.OnBackgroundInk
+		}
+		text := unison.NewText(strconv.Itoa(number), &unison.TextDecoration{Font: textEditor.Font, OnBackgroundInk: ink})
+		text.Draw(gc, rect.Right()-gutterMargin-text.Width(), textEditor.FromSelectionIndex(start).Y+text.Baseline())
+		end := lineEnd(runes, start)
+		if end >= len(runes) || end+1 > last {
+			return
+		}
+		start = end + 1
+		number++
+	}
+}
+
+// A light band over the line of the cursor, wrapped lines included
+func drawCurrentLine(gc *unison.Canvas) {
+	if textEditor.HasSelectionRange() || textEditor.ObscurementRune != 0 {
+		return
+	}
+	runes := []rune(textEditor.Text())
+	cursor, _ := textEditor.Selection()
+	top := textEditor.FromSelectionIndex(lineStart(runes, cursor)).Y
+	bottom := textEditor.FromSelectionIndex(lineEnd(runes, cursor)).Y + textEditor.Font.LineHeight()
+	content := textEditor.ContentRect(false)
+	rect := unison.NewRect(content.X, top, content.Width, bottom-top)
+	gc.DrawRect(rect, currentLineInk.Paint(gc, rect, paintstyle.Fill))
+}
@@ -9,28 +9,59 @@ package ui
 import (
 	"SimpleTwofishEditor/assets"
 	"SimpleTwofishEditor/crypto"
+	"fmt"
 	"github.com/richardwilkes/unison"
 	"github.com/richardwilkes/unison/enums/align"
+	"os"
 )
 
 const (
 	PwdSet = iota + 1
 	PwdGet
+	PwdAdd
+	PwdIdentitySet
+	PwdIdentityGet
+	PwdExport
+	PwdImport
+	PwdSearch
 )
 
 const inpTextSize = 200
 const obscureRune = 0x2a
+const strengthMaxBits = 100
 
 var pwdDialog *unison.Dialog
 var inpUpper *unison.Field
 var inpLower *unison.Field
+var strengthBar *unison.ProgressBar
+var strengthLabel *unison.Label
 var okButton *unison.Button
 var cancelButton *unison.Button
+var factorMenu *unison.PopupMenu[string]
+var keyfileField *unison.Field
+var keyfileButton *unison.Button
+var labelField *unison.Field
 var dialogMode int
+var dialogFactors int
+var lastKeyfile = ""
+var enteredPassphrase []byte
 
 func ShowPasswordDialog(mode int) int {
+	factors := crypto.FactorPassword
+	if mode == PwdSet && crypto.IsValid() {
+		factors = crypto.Factors()
+	}
+	return ShowPasswordDialogWithFactors(mode, factors)
+}
+
+// Preselect the factors (password, keyfile) required to unlock a file
+func ShowPasswordDialogWithFactors(mode int, factors int) int {
 	var err error
 	dialogMode = mode
+	dialogFactors = factors
+	if dialogFactors == 0 {
+		dialogFactors = crypto.FactorPassword
+	}
 	pwdDialog, err = newPasswordDialog()
 	if err != nil {
 		panic(err)
@@ -51,11 +82,33 @@ func newPasswordDialog() (*unison.Dialog, error) {
 			wnd.SetTitle(assets.CapPwdSet)
 		} else if dialogMode == PwdGet {
 			wnd.SetTitle(assets.CapPwdGet)
+		} else if dialogMode == PwdAdd {
+			wnd.SetTitle(assets.CapPwdAdd)
+		} else if dialogMode == PwdExport {
+			wnd.SetTitle(assets.CapExportPassphrase)
+		} else if dialogMode == PwdImport {
+			wnd.SetTitle(assets.CapImportPassphrase)
+		} else if dialogMode == PwdSearch {
+			wnd.SetTitle(assets.CapSearchPassword)
+		} else if isPassphraseMode() {
+			wnd.SetTitle(assets.CapIdentityPassphrase)
 		}
 		okButton = dialog.Button(unison.ModalResponseOK)
 		okButton.ClickCallback = func() {
-			crypto.Push([]byte(inpUpper.Text()))
-			pwdDialog.StopModal(unison.ModalResponseOK)
+			var ok bool
+			switch {
+			case dialogMode == PwdAdd:
+				ok = addFactors()
+			case isPassphraseMode():
+				clear(enteredPassphrase)
+				enteredPassphrase = []byte(inpUpper.Text())
+				ok = true
+			default:
+				ok = pushFactors()
+			}
+			if ok {
+				pwdDialog.StopModal(unison.ModalResponseOK)
+			}
 		}
 		cancelButton = dialog.Button(unison.ModalResponseCancel)
 		cancelButton.ClickCallback = func() { pwdDialog.StopModal(unison.ModalResponseCancel) }
@@ -98,34 +151,235 @@ func newPasswordMessagePanel() *unison.Panel {
 		VSpan:   2,
 		VAlign:  align.Middle,
 	})
+	lblFactors := unison.NewLabel()
+	lblFactors.Font = unison.LabelFont
+	lblFactors.SetTitle(assets.CapUnlockWith)
+	factorMenu = unison.NewPopupMenu[string]()
+	factorMenu.AddItem(assets.TxtFactors[:]...)
+	factorMenu.SelectIndex(dialogFactors - 1)
+	factorMenu.SelectionChangedCallback = func(_ *unison.PopupMenu[string]) {
+		dialogFactors = factorMenu.SelectedIndex() + 1
+		updateFactorControls()
+	}
+	lblKeyfile := unison.NewLabel()
+	lblKeyfile.Font = unison.LabelFont
+	lblKeyfile.SetTitle(assets.CapKeyfile)
+	keyfilePanel := unison.NewPanel()
+	keyfilePanel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+	})
+	keyfileField = unison.NewField()
+	keyfileField.Font = unison.FieldFont
+	keyfileField.MinimumTextWidth = inpTextSize
+	keyfileField.SetText(lastKeyfile)
+	keyfileField.ModifiedCallback = func(_, _ *unison.FieldState) {
+		updateOkButton()
+	}
+	keyfileButton = unison.NewButton()
+	keyfileButton.SetTitle(assets.CapChoose)
+	keyfileButton.ClickCallback = func() { chooseKeyfile() }
+	keyfilePanel.AddChild(keyfileField)
+	keyfilePanel.AddChild(keyfileButton)
+	if dialogMode == PwdAdd {
+		lblLabel := unison.NewLabel()
+		lblLabel.Font = unison.LabelFont
+		lblLabel.SetTitle(assets.CapSlotLabel)
+		labelField = unison.NewField()
+		labelField.Font = unison.FieldFont
+		labelField.MinimumTextWidth = inpTextSize
+		panel.AddChild(lblLabel)
+		panel.AddChild(labelField)
+	}
+	if !isPassphraseMode() {
+		panel.AddChild(lblFactors)
+		panel.AddChild(factorMenu)
+		panel.AddChild(lblKeyfile)
+		panel.AddChild(keyfilePanel)
+	}
 	panel.AddChild(lblUpper)
 	panel.AddChild(inpUpper)
-	if dialogMode == PwdSet {
+	if isNewPassword() {
 		panel.AddChild(lblLower)
 		panel.AddChild(inpLower)
+		lblStrength := unison.NewLabel()
+		lblStrength.Font = unison.LabelFont
+		lblStrength.SetTitle(assets.CapPwdStrength)
+		strengthBar = unison.NewProgressBar(strengthMaxBits)
+		strengthBar.SetLayoutData(&unison.FlexLayoutData{
+			HAlign: align.Fill,
+			VAlign: align.Middle,
+			HGrab:  true,
+		})
+		strengthLabel = unison.NewLabel()
+		strengthLabel.Font = unison.LabelFont
+		strengthLabel.SetTitle(" ")
+		strengthLabel.SetLayoutData(&unison.FlexLayoutData{
+			HSpan:  2,
+			VSpan:  1,
+			HAlign: align.Fill,
+			HGrab:  true,
+		})
+		genButton := unison.NewButton()
+		genButton.SetTitle(assets.CapGeneratorMenu)
+		genButton.SetLayoutData(&unison.FlexLayoutData{
+			HSpan:  2,
+			VSpan:  1,
+			HAlign: align.End,
+		})
+		genButton.ClickCallback = func() {
+			if password := ShowGeneratorDialog(assets.CapUse, true); password != "" {
+				inpUpper.SetText(password)
+				inpLower.SetText(password)
+			}
+		}
+		panel.AddChild(lblStrength)
+		panel.AddChild(strengthBar)
+		panel.AddChild(strengthLabel)
+		panel.AddChild(genButton)
 	}
+	updateFactorControls()
 	panel.Pack()
 	return panel
 }
 
+func isNewPassword() bool {
+	return dialogMode == PwdSet || dialogMode == PwdAdd || dialogMode == PwdIdentitySet || dialogMode == PwdExport
+}
+
+// Identities and foreign file formats are protected by a passphrase only, it is not pushed into the enclave.
+// The password for a search must not replace the one of the open document either.
+func isPassphraseMode() bool {
+	return dialogMode == PwdIdentitySet || dialogMode == PwdIdentityGet || dialogMode == PwdExport || dialogMode == PwdImport ||
+		dialogMode == PwdSearch
+}
+
 func inpUpperModifiedCallback(_, after *unison.FieldState) {
-	okButton.SetEnabled(false)
-	if dialogMode == PwdSet {
-		if after.Text != "" && after.Text == inpLower.Text() {
-			okButton.SetEnabled(true)
+	if isNewPassword() {
+		updatePasswordStrength(after.Text)
+	}
+	updateOkButton()
+}
+
+func inpLowerModifiedCallback(_, _ *unison.FieldState) {
+	updateOkButton()
+}
+
+func updateFactorControls() {
+	usePassword := dialogFactors&crypto.FactorPassword != 0
+	useKeyfile := dialogFactors&crypto.FactorKeyfile != 0
+	inpUpper.SetEnabled(usePassword)
+	inpLower.SetEnabled(usePassword)
+	keyfileField.SetEnabled(useKeyfile)
+	keyfileButton.SetEnabled(useKeyfile)
+	updateOkButton()
+}
+
+func updateOkButton() {
+	ok := true
+	if dialogFactors&crypto.FactorPassword != 0 {
+		p := inpUpper.Text()
+		if isNewPassword() {
+			ok = p != "" && p == inpLower.Text() && checkPasswordPolicy(p) == ""
+		} else {
+			ok = p != ""
 		}
+	}
+	if dialogFactors&crypto.FactorKeyfile != 0 && keyfileField.Text() == "" {
+		ok = false
+	}
+	okButton.SetEnabled(ok)
+}
+
+func chooseKeyfile() {
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetCanChooseFiles(true)
+	if dialog.RunModal() == true {
+		keyfileField.SetText(dialog.Path())
+	}
+}
+
+// Push password and keyfile into the enclave, returns false if the keyfile cannot be read
+func pushFactors() bool {
+	keyfile, ok := readKeyfile()
+	if !ok {
+		return false
+	}
+	if dialogFactors&crypto.FactorPassword != 0 {
+		crypto.Push([]byte(inpUpper.Text()))
 	} else {
-		if dialogMode == PwdGet {
-			if after.Text != "" {
-				okButton.SetEnabled(true)
-			}
+		crypto.Push(nil)
+	}
+	if keyfile != nil {
+		crypto.PushKeyfile(keyfile)
+		clear(keyfile)
+	}
+	return true
+}
+
+// Add password and keyfile as another key slot of the document, the enclave is left alone
+func addFactors() bool {
+	var password []byte
+	keyfile, ok := readKeyfile()
+	if !ok {
+		return false
+	}
+	if dialogFactors&crypto.FactorPassword != 0 {
+		password = []byte(inpUpper.Text())
+	}
+	err := crypto.AddKeySlot(labelField.Text(), password, keyfile)
+	clear(password)
+	clear(keyfile)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrKeySlotsUpdate, err)
+		return false
+	}
+	return true
+}
+
+func readKeyfile() ([]byte, bool) {
+	if dialogFactors&crypto.FactorKeyfile == 0 {
+		return nil, true
+	}
+	keyfile, err := os.ReadFile(keyfileField.Text())
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrKeyfileRead, err)
+		return nil, false
+	}
+	lastKeyfile = keyfileField.Text()
+	return keyfile, true
+}
+
+func updatePasswordStrength(p string) {
+	strength := crypto.EstimatePasswordStrength(p)
+	strengthBar.SetCurrent(float32(min(strength.Entropy, strengthMaxBits)))
+	text := " "
+	if p != "" {
+		text = assets.TxtStrength[strength.Rating()]
+		if strength.Known {
+			text = assets.MsgKnownWeakPassword
 		}
 	}
+	violation := checkPasswordPolicy(p)
+	if violation != "" && p != "" {
+		text = violation
+	}
+	strengthLabel.SetTitle(text)
 }
 
-func inpLowerModifiedCallback(_, after *unison.FieldState) {
-	okButton.SetEnabled(false)
-	if after.Text != "" && after.Text == inpUpper.Text() {
-		okButton.SetEnabled(true)
+// Returns a message describing the first policy violation, or an empty string
+func checkPasswordPolicy(p string) string {
+	strength := crypto.EstimatePasswordStrength(p)
+	if strength.Length < pwdPolicy.MinLength {
+		return fmt.Sprintf(assets.MsgPolicyLength, pwdPolicy.MinLength)
+	}
+	if strength.ClassCount() < pwdPolicy.MinClasses {
+		return fmt.Sprintf(assets.MsgPolicyClasses, pwdPolicy.MinClasses)
+	}
+	if pwdPolicy.DictionaryCheck && strength.Known {
+		return assets.MsgKnownWeakPassword
 	}
+	return ""
 }
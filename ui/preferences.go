@@ -7,6 +7,7 @@ package ui
 
 import (
 	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
 	"encoding/json"
 	"github.com/richardwilkes/unison"
 	"io"
@@ -25,10 +26,18 @@ func savePreferences() {
 		fontsize = size
 	}
 	prefs := preferences{
-		WindowRect: rect,
-		FontName:   fontname,
-		FontSize:   fontsize,
-		LastFolder: lastOpenFolder,
+		WindowRect:       rect,
+		FontName:         fontname,
+		FontSize:         fontsize,
+		LastFolder:       lastOpenFolder,
+		Policy:           pwdPolicy,
+		Generator:        genSettings,
+		ClipboardTimeout: clipboardTimeout,
+		IdentityFile:     identityFile,
+		Compress:         compressDocuments,
+		Preview:          showPreview,
+		LineNumbers:      showLineNumbers,
+		NoWordWrap:       !wordWrap,
 	}
 	j, err := json.Marshal(prefs)
 	if err == nil {
@@ -59,14 +68,59 @@ func loadPreferences() preferences {
 	if prefs.FontSize == "" {
 		prefs.FontSize = editorFontSize
 	}
+	if prefs.Policy == (passwordPolicy{}) {
+		prefs.Policy = defaultPasswordPolicy
+	}
+	if prefs.ClipboardTimeout == "" {
+		prefs.ClipboardTimeout = clipboardTimeoutDefault
+	}
+	if prefs.Generator == (generatorSettings{}) {
+		prefs.Generator = defaultGeneratorSettings
+	}
 	return prefs
 }
 
 type preferences struct {
-	WindowRect unison.Rect
-	FontName   string
-	FontSize   string
-	LastFolder string
+	WindowRect       unison.Rect
+	FontName         string
+	FontSize         string
+	LastFolder       string
+	Policy           passwordPolicy
+	Generator        generatorSettings
+	ClipboardTimeout string
+	IdentityFile     string
+	Compress         bool
+	Preview          bool
+	LineNumbers      bool
+	NoWordWrap       bool // wrap is the default
+}
+
+type passwordPolicy struct {
+	MinLength       int
+	MinClasses      int
+	DictionaryCheck bool
+}
+
+var defaultPasswordPolicy = passwordPolicy{
+	MinLength:       8,
+	MinClasses:      2,
+	DictionaryCheck: true,
+}
+
+type generatorSettings struct {
+	Kind      int
+	Length    int
+	Classes   int
+	Words     int
+	Separator string
+}
+
+var defaultGeneratorSettings = generatorSettings{
+	Kind:      GenPassword,
+	Length:    20,
+	Classes:   crypto.ClassLower | crypto.ClassUpper | crypto.ClassDigit | crypto.ClassSymbol,
+	Words:     6,
+	Separator: "-",
 }
 
 const preferencesFileName = "org.janbuchholz.simpletwofisheditor.json"
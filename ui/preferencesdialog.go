@@ -0,0 +1,154 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Preferences dialog, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/check"
+)
+
+var minLengths = []int{1, 4, 6, 8, 10, 12, 14, 16, 20, 24, 32}
+var minClasses = []int{1, 2, 3, 4}
+
+var (
+	minLengthMenu  *unison.PopupMenu[int]
+	minClassesMenu *unison.PopupMenu[int]
+	dictionaryBox  *unison.CheckBox
+	clipboardMenu  *unison.PopupMenu[string]
+	compressBox    *unison.CheckBox
+)
+
+var compressDocuments = false
+
+func PreferencesDialog(item unison.MenuItem) {
+	dialog, err := unison.NewDialog(nil, nil, newPreferencesPanel(),
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption())
+	if err == nil {
+		wnd := dialog.Window()
+		wnd.SetTitle(item.Title())
+		if len(titleIcons) > 0 {
+			wnd.SetTitleIcons(titleIcons)
+		}
+		if dialog.RunModal() == unison.ModalResponseOK {
+			applyPreferences()
+		}
+	}
+}
+
+func newPreferencesPanel() *unison.Panel {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	addPreferencesHeader(panel, assets.CapPasswordPolicy)
+	minLengthMenu = unison.NewPopupMenu[int]()
+	for _, n := range minLengths {
+		minLengthMenu.AddItem(n)
+	}
+	minLengthMenu.Select(pwdPolicy.MinLength)
+	addPreferencesRow(panel, assets.CapMinLength, minLengthMenu.AsPanel())
+	minClassesMenu = unison.NewPopupMenu[int]()
+	for _, n := range minClasses {
+		minClassesMenu.AddItem(n)
+	}
+	minClassesMenu.Select(pwdPolicy.MinClasses)
+	addPreferencesRow(panel, assets.CapMinClasses, minClassesMenu.AsPanel())
+	dictionaryBox = unison.NewCheckBox()
+	dictionaryBox.SetTitle(assets.CapDictionaryCheck)
+	dictionaryBox.State = check.FromBool(pwdPolicy.DictionaryCheck)
+	dictionaryBox.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  2,
+		VSpan:  1,
+		HAlign: align.Start,
+	})
+	panel.AddChild(dictionaryBox)
+	addPreferencesHeader(panel, assets.CapClipboard)
+	clipboardMenu = unison.NewPopupMenu[string]()
+	clipboardMenu.AddItem(clipboardTimeouts...)
+	clipboardMenu.Select(clipboardTimeout)
+	addPreferencesRow(panel, assets.CapClipboardTimeout, clipboardMenu.AsPanel())
+	addPreferencesHeader(panel, assets.CapFiles)
+	compressBox = unison.NewCheckBox()
+	compressBox.SetTitle(assets.CapCompress)
+	compressBox.State = check.FromBool(compressDocuments)
+	compressBox.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  2,
+		VSpan:  1,
+		HAlign: align.Start,
+	})
+	panel.AddChild(compressBox)
+	// Compression leaks the redundancy of the text through the file size
+	hint := unison.NewLabel()
+	hint.Font = unison.LabelFont
+	hint.SetTitle(assets.TxtCompressHint)
+	hint.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  2,
+		VSpan:  1,
+		HAlign: align.Start,
+	})
+	panel.AddChild(hint)
+	panel.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Width: 300},
+		HSpan:   1,
+		VSpan:   1,
+		VAlign:  align.Middle,
+	})
+	return panel
+}
+
+func addPreferencesHeader(panel *unison.Panel, title string) {
+	header := unison.NewLabel()
+	header.Font = unison.SystemFont
+	header.SetTitle(title)
+	header.SetLayoutData(&unison.FlexLayoutData{
+		HSpan:  2,
+		VSpan:  1,
+		HAlign: align.Start,
+	})
+	panel.AddChild(header)
+}
+
+func addPreferencesRow(panel *unison.Panel, title string, control *unison.Panel) {
+	label := unison.NewLabel()
+	label.Font = unison.LabelFont
+	label.SetTitle(title)
+	label.SetLayoutData(&unison.FlexLayoutData{
+		HAlign: align.End,
+		VAlign: align.Middle,
+	})
+	panel.AddChild(label)
+	panel.AddChild(control)
+}
+
+func applyPreferences() {
+	if n, ok := minLengthMenu.Selected(); ok {
+		pwdPolicy.MinLength = n
+	}
+	if n, ok := minClassesMenu.Selected(); ok {
+		pwdPolicy.MinClasses = n
+	}
+	pwdPolicy.DictionaryCheck = dictionaryBox.State == check.On
+	if timeout, ok := clipboardMenu.Selected(); ok {
+		clipboardTimeout = timeout
+	}
+	compressDocuments = compressBox.State == check.On
+	setCompression()
+}
+
+func setCompression() {
+	if compressDocuments {
+		crypto.SetCompression(crypto.CompressionDeflate)
+	} else {
+		crypto.SetCompression(crypto.CompressionNone)
+	}
+}
@@ -0,0 +1,142 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Markdown preview of the text, rendered in memory, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"slices"
+	"strings"
+	"time"
+)
+
+const previewDelay = 300 * time.Millisecond
+
+var showPreview = false
+var previewPanel *unison.Panel
+var previewMarkdown *unison.Markdown
+var previewSerial = 0
+
+func createPreviewPanel() {
+	previewMarkdown = unison.NewMarkdown(true)
+	scroller := unison.NewScrollPanel()
+	scroller.SetContent(previewMarkdown, behavior.Fill, behavior.Fill)
+	scroller.SetLayoutData(&unison.FlexLayoutData{
+		HAlign: align.Fill,
+		VAlign: align.Fill,
+		HGrab:  true,
+		VGrab:  true,
+	})
+	previewPanel = scroller.AsPanel()
+}
+
+// The preview is shown next to the editor, locked documents stay read-only in the editor
+func editPreview() {
+	showPreview = !showPreview
+	updateDocumentView()
+	updatePreview()
+}
+
+// Changes of the text are rendered once typing pauses
+func schedulePreview() {
+	if !showPreview {
+		return
+	}
+	previewSerial++
+	serial := previewSerial
+	unison.InvokeTaskAfter(func() {
+		if serial == previewSerial {
+			updatePreview()
+		}
+	}, previewDelay)
+}
+
+func updatePreview() {
+	if !showPreview {
+		previewMarkdown.SetContent("", 0)
+		return
+	}
+	previewMarkdown.SetContent(previewText(textEditor.Text()), 0)
+	previewPanel.MarkForLayoutAndRedraw()
+}
+
+// Images would be loaded from disk or from the web. The text is parsed like Unison does, the "!" of each image
+// is escaped so it is shown as a link instead
+func previewText(content string) string {
+	src := []byte(content)
+	starts := &imageStarts{}
+	newPreviewParser(parser.WithInlineParsers(util.Prioritized(starts, 199))).Parse(text.NewReader(src))
+	if len(starts.offsets) == 0 {
+		return content
+	}
+	slices.Sort(starts.offsets)
+	var b strings.Builder
+	last := 0
+	for _, offset := range slices.Compact(starts.offsets) {
+		b.Write(src[last:offset])
+		b.WriteByte('\\')
+		last = offset
+	}
+	b.Write(src[last:])
+	// Should never happen, the text is shown without any formatting then
+	if countImages([]byte(b.String())) > 0 {
+		return escapeMarkdown(content)
+	}
+	return b.String()
+}
+
+func newPreviewParser(options ...parser.Option) parser.Parser {
+	return goldmark.New(goldmark.WithExtensions(extension.GFM), goldmark.WithParserOptions(options...)).Parser()
+}
+
+// Runs before the link parser of goldmark and records where it may start an image. Outside of an image an
+// escaped "!" is shown as it is
+type imageStarts struct {
+	offsets []int
+}
+
+func (s *imageStarts) Trigger() []byte {
+	return []byte{'!'}
+}
+
+func (s *imageStarts) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	if line, segment := block.PeekLine(); len(line) > 1 && line[1] == '[' {
+		s.offsets = append(s.offsets, segment.Start)
+	}
+	return nil
+}
+
+func countImages(src []byte) int {
+	count := 0
+	doc := newPreviewParser().Parse(text.NewReader(src))
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == ast.KindImage {
+			count++
+		}
+		return ast.WalkContinue, nil
+	})
+	return count
+}
+
+// Every ASCII punctuation character may be escaped in Markdown
+func escapeMarkdown(content string) string {
+	var b strings.Builder
+	for _, r := range content {
+		if r < 128 && strings.ContainsRune("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
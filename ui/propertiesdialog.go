@@ -0,0 +1,168 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Document properties dialog: title, author, tags, free-form properties and length hiding, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"slices"
+	"strings"
+	"time"
+)
+
+var propTitleField *unison.Field
+var propAuthorField *unison.Field
+var propTagsField *unison.Field
+var propPropertiesField *unison.Field
+var propPaddingMenu *unison.PopupMenu[string]
+
+func ShowPropertiesDialog() {
+	m := crypto.DocumentMetadata()
+	dialog, err := unison.NewDialog(nil, nil, newPropertiesPanel(m),
+		[]*unison.DialogButtonInfo{unison.NewOKButtonInfo(), unison.NewCancelButtonInfo()},
+		unison.NotResizableWindowOption())
+	if err != nil {
+		panic(err)
+	}
+	wnd := dialog.Window()
+	wnd.SetTitle(assets.CapPropertiesTitle)
+	if len(titleIcons) > 0 {
+		wnd.SetTitleIcons(titleIcons)
+	}
+	dialog.Button(unison.ModalResponseOK).SetEnabled(!isReadOnly)
+	if dialog.RunModal() != unison.ModalResponseOK {
+		return
+	}
+	m.Title = propTitleField.Text()
+	m.Author = propAuthorField.Text()
+	m.Tags = strings.Split(propTagsField.Text(), ",")
+	m.Properties = parseProperties(propPropertiesField.Text())
+	before := crypto.DocumentMetadata()
+	crypto.SetDocumentMetadata(m)
+	if !sameMetadata(before, crypto.DocumentMetadata()) {
+		isModified = true
+	}
+	updateSyntax()
+	updateWordWrap()
+	if p := propPaddingMenu.SelectedIndex(); p >= 0 && p != crypto.DocumentPadding() {
+		crypto.SetDocumentPadding(p)
+		isModified = true
+	}
+}
+
+func newPropertiesPanel(m crypto.Metadata) *unison.Panel {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	propTitleField = addPropertyField(panel, assets.CapMetaTitle, m.Title, "")
+	propAuthorField = addPropertyField(panel, assets.CapMetaAuthor, m.Author, "")
+	propTagsField = addPropertyField(panel, assets.CapMetaTags, strings.Join(m.Tags, ", "), assets.TxtTagsHint)
+	addPropertyLabel(panel, assets.CapMetaCreated, formatTimestamp(m.Created))
+	addPropertyLabel(panel, assets.CapMetaModified, formatTimestamp(m.Modified))
+	var lines []string
+	for _, p := range m.Properties {
+		lines = append(lines, p.Key+": "+p.Value)
+	}
+	newPropertyCaption(panel, assets.CapMetaProperties)
+	propPropertiesField = unison.NewMultiLineField()
+	propPropertiesField.Font = unison.FieldFont
+	propPropertiesField.MinimumTextWidth = 2 * inpTextSize
+	propPropertiesField.Watermark = assets.TxtPropertiesHint
+	propPropertiesField.SetText(strings.Join(lines, "\n"))
+	propPropertiesField.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Height: 100},
+		HAlign:  align.Fill,
+		VAlign:  align.Fill,
+		HGrab:   true,
+	})
+	panel.AddChild(propPropertiesField)
+	newPropertyCaption(panel, assets.CapPadding)
+	propPaddingMenu = unison.NewPopupMenu[string]()
+	propPaddingMenu.AddItem(assets.TxtPadding[:]...)
+	propPaddingMenu.SelectIndex(crypto.DocumentPadding())
+	panel.AddChild(propPaddingMenu)
+	return panel
+}
+
+func newPropertyCaption(panel *unison.Panel, title string) {
+	lbl := unison.NewLabel()
+	lbl.Font = unison.LabelFont
+	lbl.SetTitle(title)
+	lbl.SetLayoutData(&unison.FlexLayoutData{HAlign: align.End, VAlign: align.Start})
+	panel.AddChild(lbl)
+}
+
+func addPropertyField(panel *unison.Panel, title string, text string, hint string) *unison.Field {
+	newPropertyCaption(panel, title)
+	field := unison.NewField()
+	field.Font = unison.FieldFont
+	field.MinimumTextWidth = 2 * inpTextSize
+	field.Watermark = hint
+	field.SetText(text)
+	panel.AddChild(field)
+	return field
+}
+
+func addPropertyLabel(panel *unison.Panel, title string, text string) {
+	newPropertyCaption(panel, title)
+	lbl := unison.NewLabel()
+	lbl.Font = unison.LabelFont
+	lbl.SetTitle(text)
+	panel.AddChild(lbl)
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return assets.TxtNotSaved
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// One property per line, name and value are separated by the first colon
+func parseProperties(text string) []crypto.Property {
+	var properties []crypto.Property
+	for _, line := range strings.Split(text, "\n") {
+		key, value, _ := strings.Cut(line, ":")
+		properties = append(properties, crypto.Property{Key: key, Value: value})
+	}
+	return properties
+}
+
+func sameMetadata(a, b crypto.Metadata) bool {
+	return a.Title == b.Title && a.Author == b.Author && slices.Equal(a.Tags, b.Tags) && slices.Equal(a.Properties, b.Properties)
+}
+
+// Value of a document property, the key is matched ignoring case
+func documentProperty(key string) (string, bool) {
+	for _, p := range crypto.DocumentMetadata().Properties {
+		if strings.EqualFold(p.Key, key) {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// An empty value removes the property, the document is modified if the property changes
+func setDocumentProperty(key string, value string) {
+	m := crypto.DocumentMetadata()
+	m.Properties = slices.DeleteFunc(m.Properties, func(p crypto.Property) bool {
+		return strings.EqualFold(p.Key, key)
+	})
+	if value != "" {
+		m.Properties = append(m.Properties, crypto.Property{Key: key, Value: value})
+	}
+	before := crypto.DocumentMetadata()
+	crypto.SetDocumentMetadata(m)
+	if !sameMetadata(before, crypto.DocumentMetadata()) {
+		isModified = true
+	}
+}
@@ -0,0 +1,320 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Records view: structured secrets of a document in a table, each field with a copy button,
+// using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"github.com/richardwilkes/toolbox/tid"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"slices"
+)
+
+const (
+	recordColumnTitle = iota
+	recordColumnUsername
+	recordColumnURL
+)
+
+// Working copy of the records of the open document
+var docRecords []crypto.Record
+var showRecords = false
+var editorPanel *unison.Panel
+var recordsPanel *unison.Panel
+var recordsTable *unison.Table[*recordRow]
+var recordDetail *unison.Panel
+var newRecordButton *unison.Button
+var deleteRecordButton *unison.Button
+
+// Rows of the table refer to the working copy by index, the table is rebuilt when records are added or deleted
+type recordRow struct {
+	id    tid.TID
+	index int
+}
+
+func (r *recordRow) CloneForTarget(_ unison.Paneler, _ *recordRow) *recordRow {
+	return &recordRow{id: tid.MustNewTID('r'), index: r.index}
+}
+
+func (r *recordRow) ID() tid.TID                { return r.id }
+func (r *recordRow) Parent() *recordRow         { return nil }
+func (r *recordRow) SetParent(_ *recordRow)     {}
+func (r *recordRow) CanHaveChildren() bool      { return false }
+func (r *recordRow) Children() []*recordRow     { return nil }
+func (r *recordRow) SetChildren(_ []*recordRow) {}
+func (r *recordRow) IsOpen() bool               { return false }
+func (r *recordRow) SetOpen(_ bool)             {}
+
+func (r *recordRow) CellDataForSort(col int) string {
+	if r.index >= len(docRecords) {
+		return ""
+	}
+	rec := docRecords[r.index]
+	switch col {
+	case recordColumnTitle:
+		return rec.Title
+	case recordColumnUsername:
+		return rec.Username
+	case recordColumnURL:
+		return rec.URL
+	}
+	return ""
+}
+
+func (r *recordRow) ColumnCell(_, col int, foreground, _ unison.Ink, _, _, _ bool) unison.Paneler {
+	lbl := unison.NewLabel()
+	lbl.OnBackgroundInk = foreground
+	lbl.SetTitle(r.CellDataForSort(col))
+	return lbl
+}
+
+func createRecordsPanel() *unison.Panel {
+	recordsPanel = unison.NewPanel()
+	recordsPanel.SetLayout(&unison.FlexLayout{
+		Columns:  1,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	recordsPanel.SetLayoutData(&unison.FlexLayoutData{
+		HAlign: align.Fill,
+		VAlign: align.Fill,
+		HGrab:  true,
+		VGrab:  true,
+	})
+	recordsTable = unison.NewTable[*recordRow](&unison.SimpleTableModel[*recordRow]{})
+	recordsTable.Columns = make([]unison.ColumnInfo, 3)
+	for i := range recordsTable.Columns {
+		recordsTable.Columns[i].ID = i
+		recordsTable.Columns[i].Minimum = 60
+		recordsTable.Columns[i].Maximum = 10000
+	}
+	recordsTable.SelectionChangedCallback = func() { showRecordDetail() }
+	header := unison.NewTableHeader[*recordRow](recordsTable,
+		unison.NewTableColumnHeader[*recordRow](assets.CapRecordTitle, ""),
+		unison.NewTableColumnHeader[*recordRow](assets.CapRecordUsername, ""),
+		unison.NewTableColumnHeader[*recordRow](assets.CapRecordURL, ""),
+	)
+	header.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, VAlign: align.Fill, HGrab: true})
+	recordsPanel.AddChild(header)
+	scroller := unison.NewScrollPanel()
+	scroller.SetContent(recordsTable, behavior.Fill, behavior.Fill)
+	scroller.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Height: 120},
+		HAlign:  align.Fill,
+		VAlign:  align.Fill,
+		HGrab:   true,
+		VGrab:   true,
+	})
+	recordsPanel.AddChild(scroller)
+	buttons := unison.NewPanel()
+	buttons.SetLayout(&unison.FlowLayout{HSpacing: unison.StdHSpacing})
+	newRecordButton = unison.NewButton()
+	newRecordButton.SetTitle(assets.CapNewRecord)
+	newRecordButton.ClickCallback = func() { newRecord() }
+	buttons.AddChild(newRecordButton)
+	deleteRecordButton = unison.NewButton()
+	deleteRecordButton.SetTitle(assets.CapDeleteRecord)
+	deleteRecordButton.ClickCallback = func() { deleteRecord() }
+	buttons.AddChild(deleteRecordButton)
+	recordsPanel.AddChild(buttons)
+	recordDetail = unison.NewPanel()
+	recordDetail.SetLayout(&unison.FlexLayout{
+		Columns:  4,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	recordDetail.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, HGrab: true})
+	recordsPanel.AddChild(recordDetail)
+	return recordsPanel
+}
+
+// Documents with records are shown in the records view
+func loadRecords() {
+	docRecords = crypto.DocumentRecords()
+	totpDirty = true
+	updateRecordsTable(-1)
+	setRecordsView(len(docRecords) > 0)
+}
+
+func setRecordsView(show bool) {
+	showRecords = show
+	updateDocumentView()
+}
+
+func updateRecordsTable(selected int) {
+	rows := make([]*recordRow, len(docRecords))
+	for i := range docRecords {
+		rows[i] = &recordRow{id: tid.MustNewTID('r'), index: i}
+	}
+	recordsTable.SetRootRows(rows)
+	recordsTable.SizeColumnsToFit(true)
+	if selected >= 0 && selected < len(rows) {
+		recordsTable.SelectByIndex(selected)
+	}
+	showRecordDetail()
+}
+
+func selectedRecord() int {
+	index := recordsTable.FirstSelectedRowIndex()
+	if index < 0 || index >= len(docRecords) {
+		return -1
+	}
+	return index
+}
+
+func storeRecords() {
+	crypto.SetDocumentRecords(docRecords)
+	isModified = true
+	totpDirty = true
+	recordsTable.MarkForRedraw()
+}
+
+func newRecord() {
+	if isLocked {
+		return
+	}
+	docRecords = append(docRecords, crypto.Record{Title: assets.TxtNewRecord})
+	storeRecords()
+	updateRecordsTable(len(docRecords) - 1)
+}
+
+func deleteRecord() {
+	index := selectedRecord()
+	if index < 0 || isLocked {
+		return
+	}
+	if dialogToConfirm(assets.CapDeleteRecord, assets.MsgDeleteRecord, assets.MsgDeleteRecordDetail) != unison.ModalResponseOK {
+		return
+	}
+	docRecords = slices.Delete(docRecords, index, index+1)
+	storeRecords()
+	updateRecordsTable(-1)
+}
+
+// The form is rebuilt for the record selected, fields can be changed while the document is unlocked
+func showRecordDetail() {
+	recordDetail.RemoveAllChildren()
+	index := selectedRecord()
+	newRecordButton.SetEnabled(!isLocked)
+	deleteRecordButton.SetEnabled(index >= 0 && !isLocked)
+	if index >= 0 {
+		rec := &docRecords[index]
+		addRecordField(recordDetail, assets.CapRecordTitle, &rec.Title, false, false)
+		addRecordField(recordDetail, assets.CapRecordUsername, &rec.Username, false, false)
+		addRecordField(recordDetail, assets.CapRecordPassword, &rec.Password, true, false)
+		addRecordField(recordDetail, assets.CapRecordURL, &rec.URL, false, false)
+		addRecordField(recordDetail, assets.CapRecordNotes, &rec.Notes, false, true)
+		for i := range rec.Fields {
+			addCustomField(recordDetail, index, i)
+		}
+		addButton := unison.NewButton()
+		addButton.SetTitle(assets.CapAddField)
+		addButton.SetEnabled(!isLocked)
+		addButton.ClickCallback = func() {
+			docRecords[index].Fields = append(docRecords[index].Fields, crypto.RecordField{})
+			showRecordDetail()
+		}
+		addButton.SetLayoutData(&unison.FlexLayoutData{HSpan: 4, HAlign: align.End})
+		recordDetail.AddChild(addButton)
+	}
+	recordsPanel.MarkForLayoutAndRedraw()
+}
+
+// Passwords are obscured until shown, notes may have several lines
+func addRecordField(panel *unison.Panel, title string, value *string, secret bool, multiLine bool) {
+	newPropertyCaption(panel, title)
+	field := newRecordValueField(value, multiLine)
+	if secret {
+		field.ObscurementRune = obscureRune
+	}
+	panel.AddChild(field)
+	if secret {
+		showButton := unison.NewButton()
+		showButton.SetTitle(assets.CapShow)
+		showButton.ClickCallback = func() {
+			if field.ObscurementRune == 0 {
+				field.ObscurementRune = obscureRune
+				showButton.SetTitle(assets.CapShow)
+			} else {
+				field.ObscurementRune = 0
+				showButton.SetTitle(assets.CapHide)
+			}
+			field.MarkForRedraw()
+		}
+		panel.AddChild(showButton)
+	} else {
+		panel.AddChild(unison.NewPanel())
+	}
+	panel.AddChild(newCopyButton(func() string { return *value }, secret))
+}
+
+// Custom fields: name and value, their values are treated as secrets when copied
+func addCustomField(panel *unison.Panel, index int, i int) {
+	f := &docRecords[index].Fields[i]
+	name := unison.NewField()
+	name.Font = unison.FieldFont
+	name.MinimumTextWidth = inpTextSize / 2
+	name.Watermark = assets.TxtFieldName
+	name.SetText(f.Name)
+	name.SetEnabled(!isLocked)
+	name.ModifiedCallback = func(_, after *unison.FieldState) {
+		f.Name = after.Text
+		storeRecords()
+	}
+	panel.AddChild(name)
+	panel.AddChild(newRecordValueField(&f.Value, false))
+	removeButton := unison.NewButton()
+	removeButton.SetTitle(assets.CapRemove)
+	removeButton.SetEnabled(!isLocked)
+	removeButton.ClickCallback = func() {
+		docRecords[index].Fields = slices.Delete(docRecords[index].Fields, i, i+1)
+		storeRecords()
+		showRecordDetail()
+	}
+	panel.AddChild(removeButton)
+	panel.AddChild(newCopyButton(func() string { return f.Value }, true))
+}
+
+func newRecordValueField(value *string, multiLine bool) *unison.Field {
+	var field *unison.Field
+	if multiLine {
+		field = unison.NewMultiLineField()
+		field.SetLayoutData(&unison.FlexLayoutData{MinSize: unison.Size{Height: 60}, HAlign: align.Fill, HGrab: true})
+	} else {
+		field = unison.NewField()
+		field.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, HGrab: true})
+	}
+	field.Font = unison.FieldFont
+	field.MinimumTextWidth = inpTextSize
+	field.SetText(*value)
+	field.SetEnabled(!isLocked)
+	field.ModifiedCallback = func(_, after *unison.FieldState) {
+		*value = after.Text
+		storeRecords()
+	}
+	return field
+}
+
+// Copied values are cleared from the clipboard like text copied from the editor
+func newCopyButton(value func() string, sensitive bool) *unison.Button {
+	button := unison.NewButton()
+	button.SetTitle(assets.CapCopy)
+	button.ClickCallback = func() {
+		if v := value(); v != "" {
+			unison.GlobalClipboard.SetText(v)
+			clipboardCopied(sensitive)
+		}
+	}
+	return button
+}
+
+func editRecords() {
+	setRecordsView(!showRecords)
+}
@@ -0,0 +1,276 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Search dialog: search the documents of a folder or of the vault opened, decrypted in memory only,
+// using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"github.com/richardwilkes/unison/enums/check"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const searchContextLines = 2
+
+const (
+	responseSearch = unison.ModalResponseUserBase + iota
+	responseOpenResult
+)
+
+type searchResult struct {
+	file  string
+	name  string
+	match crypto.SearchMatch
+}
+
+var searchFolder = ""
+var searchResults []searchResult
+var searchField *unison.Field
+var searchRegexBox *unison.CheckBox
+var searchCaseBox *unison.CheckBox
+var searchFolderLabel *unison.Label
+var searchResultList *unison.List[string]
+var searchContextList *unison.List[string]
+var searchStatus *unison.Label
+var searchButton *unison.Button
+var openResultButton *unison.Button
+
+func ShowSearchDialog() {
+	if vaultFolder != "" {
+		searchFolder = vaultFolder
+	} else if searchFolder == "" {
+		searchFolder = lastOpenFolder
+	}
+	dialog, err := unison.NewDialog(nil, nil, newSearchPanel(),
+		[]*unison.DialogButtonInfo{
+			{Title: assets.CapSearch, ResponseCode: responseSearch},
+			{Title: assets.CapOpenResult, ResponseCode: responseOpenResult},
+			unison.NewOKButtonInfoWithTitle(assets.CapClose),
+		},
+		unison.NotResizableWindowOption())
+	if err != nil {
+		panic(err)
+	}
+	wnd := dialog.Window()
+	wnd.SetTitle(assets.CapSearchTitle)
+	if len(titleIcons) > 0 {
+		wnd.SetTitleIcons(titleIcons)
+	}
+	searchButton = dialog.Button(responseSearch)
+	searchButton.ClickCallback = func() { runSearch() }
+	searchButton.SetEnabled(false)
+	openResultButton = dialog.Button(responseOpenResult)
+	openResultButton.ClickCallback = func() { dialog.StopModal(responseOpenResult) }
+	openResultButton.SetEnabled(false)
+	showSearchResults()
+	if dialog.RunModal() == responseOpenResult {
+		openSearchResult()
+	}
+	searchResults = nil
+}
+
+func newSearchPanel() *unison.Panel {
+	panel := unison.NewPanel()
+	panel.SetLayout(&unison.FlexLayout{
+		Columns:  2,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	newPropertyCaption(panel, assets.CapSearchFor)
+	searchField = unison.NewField()
+	searchField.Font = unison.FieldFont
+	searchField.MinimumTextWidth = 2 * inpTextSize
+	searchField.ModifiedCallback = func(_, after *unison.FieldState) { searchButton.SetEnabled(after.Text != "") }
+	panel.AddChild(searchField)
+	panel.AddChild(unison.NewPanel())
+	options := unison.NewPanel()
+	options.SetLayout(&unison.FlowLayout{HSpacing: unison.StdHSpacing})
+	searchRegexBox = unison.NewCheckBox()
+	searchRegexBox.SetTitle(assets.CapRegex)
+	options.AddChild(searchRegexBox)
+	searchCaseBox = unison.NewCheckBox()
+	searchCaseBox.SetTitle(assets.CapIgnoreCase)
+	searchCaseBox.State = check.On
+	options.AddChild(searchCaseBox)
+	panel.AddChild(options)
+	newPropertyCaption(panel, assets.CapSearchIn)
+	folder := unison.NewPanel()
+	folder.SetLayout(&unison.FlexLayout{Columns: 2, HSpacing: unison.StdHSpacing})
+	searchFolderLabel = unison.NewLabel()
+	searchFolderLabel.Font = unison.LabelFont
+	searchFolderLabel.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, VAlign: align.Middle, HGrab: true})
+	folder.AddChild(searchFolderLabel)
+	chooseButton := unison.NewButton()
+	chooseButton.SetTitle(assets.CapChoose)
+	chooseButton.ClickCallback = func() { chooseSearchFolder() }
+	folder.AddChild(chooseButton)
+	folder.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, HGrab: true})
+	panel.AddChild(folder)
+	searchResultList = unison.NewList[string]()
+	searchResultList.SetAllowMultipleSelection(false)
+	searchResultList.NewSelectionCallback = func() { showSearchContext() }
+	searchResultList.DoubleClickCallback = func() {
+		if openResultButton.Enabled() {
+			openResultButton.Click()
+		}
+	}
+	addSearchList(panel, searchResultList, 180)
+	searchContextList = unison.NewList[string]()
+	searchContextList.SetAllowMultipleSelection(false)
+	addSearchList(panel, searchContextList, 90)
+	searchStatus = unison.NewLabel()
+	searchStatus.Font = unison.LabelFont
+	searchStatus.SetTitle(" ")
+	searchStatus.SetLayoutData(&unison.FlexLayoutData{HSpan: 2, HAlign: align.Fill, HGrab: true})
+	panel.AddChild(searchStatus)
+	return panel
+}
+
+func addSearchList(panel *unison.Panel, list *unison.List[string], height float32) {
+	scroller := unison.NewScrollPanel()
+	scroller.SetContent(list, behavior.Fill, behavior.Fill)
+	scroller.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Width: 3 * inpTextSize, Height: height},
+		HSpan:   2,
+		HAlign:  align.Fill,
+		VAlign:  align.Fill,
+		HGrab:   true,
+		VGrab:   true,
+	})
+	panel.AddChild(scroller)
+}
+
+func chooseSearchFolder() {
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(true)
+	dialog.SetCanChooseFiles(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetInitialDirectory(searchFolder)
+	if dialog.RunModal() != true {
+		return
+	}
+	searchFolder = dialog.Path()
+	searchResults = nil
+	showSearchResults()
+}
+
+// Documents of the vault are shown by name, the password is asked for once if any other file needs it
+func runSearch() {
+	re, err := crypto.SearchPattern(searchField.Text(), searchRegexBox.State == check.On, searchCaseBox.State == check.On)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrSearch, err)
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(searchFolder, "*."+assets.FileExtension))
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrSearch, err)
+		return
+	}
+	inVault := vaultFolder != "" && path.Clean(searchFolder) == path.Clean(vaultFolder)
+	var password []byte
+	asked := false
+	defer func() { clear(password) }()
+	searchResults = nil
+	failed := 0
+	for _, p := range files {
+		name := path.Base(p)
+		if inVault {
+			for _, e := range vaultEntries {
+				if crypto.VaultEntryFile(e.ID) == name {
+					name = e.Name
+				}
+			}
+		}
+		payload, err := os.ReadFile(p)
+		if err != nil {
+			failed++
+			continue
+		}
+		var pwd []byte
+		if !crypto.UnlocksWithVault(payload) && !crypto.UnlocksWithIdentity(payload) {
+			if !asked {
+				asked = true
+				if ShowPasswordDialog(PwdSearch) == unison.ModalResponseOK {
+					password = enteredPassphrase
+					enteredPassphrase = nil
+				}
+			}
+			if password == nil {
+				failed++
+				continue
+			}
+			pwd = password
+		}
+		matches, message := crypto.SearchPayload(payload, pwd, nil, re, searchContextLines)
+		clear(payload)
+		if message != "" {
+			failed++
+			continue
+		}
+		for _, m := range matches {
+			searchResults = append(searchResults, searchResult{file: p, name: name, match: m})
+		}
+	}
+	showSearchResults()
+	status := fmt.Sprintf(assets.TxtSearchStatus, len(searchResults), len(files))
+	if failed > 0 {
+		status += fmt.Sprintf(assets.TxtSearchFailed, failed)
+	}
+	searchStatus.SetTitle(status)
+}
+
+func showSearchResults() {
+	searchFolderLabel.SetTitle(searchFolder)
+	searchResultList.Clear()
+	for _, r := range searchResults {
+		searchResultList.Append(fmt.Sprintf(assets.TxtSearchResult, r.name, r.match.Line, r.match.Text))
+	}
+	searchResultList.MarkForLayoutAndRedraw()
+	showSearchContext()
+	searchStatus.SetTitle(" ")
+	searchStatus.Parent().MarkForLayoutAndRedraw()
+}
+
+func selectedSearchResult() (searchResult, bool) {
+	index := searchResultList.Selection.FirstSet()
+	if index < 0 || index >= len(searchResults) {
+		return searchResult{}, false
+	}
+	return searchResults[index], true
+}
+
+func showSearchContext() {
+	searchContextList.Clear()
+	r, ok := selectedSearchResult()
+	openResultButton.SetEnabled(ok)
+	if ok {
+		m := r.match
+		for i, line := range m.Before {
+			searchContextList.Append(fmt.Sprintf(assets.TxtSearchContext, m.Line-len(m.Before)+i, line))
+		}
+		searchContextList.Append(fmt.Sprintf(assets.TxtSearchMatch, m.Line, m.Text))
+		searchContextList.Select(false, len(m.Before))
+		for i, line := range m.After {
+			searchContextList.Append(fmt.Sprintf(assets.TxtSearchContext, m.Line+1+i, line))
+		}
+	}
+	searchContextList.MarkForLayoutAndRedraw()
+}
+
+func openSearchResult() {
+	r, ok := selectedSearchResult()
+	if !ok || !saveChangesFirst() {
+		return
+	}
+	openDocument(r.file)
+}
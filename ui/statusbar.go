@@ -0,0 +1,121 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Status bar: position of the cursor, counts and the state of the document,
+// using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const statusInterval = 200 * time.Millisecond
+
+var statusBar *unison.Panel
+var statusPosition *unison.Label
+var statusCounts *unison.Label
+var statusModified *unison.Label
+var statusLock *unison.Label
+var statusEncoding *unison.Label
+var statusFormat *unison.Label
+
+// Format of the file opened, files are saved in the latest format
+var containerVersion = crypto.ContainerVersionLatest
+
+// Set when the text changes, it is counted again with the next tick
+var statusDirty = true
+var statusCursor = -1
+var statusSelection = -1
+
+func createStatusBar() *unison.Panel {
+	statusBar = unison.NewPanel()
+	statusBar.SetLayout(&unison.FlexLayout{
+		Columns:  6,
+		HSpacing: 3 * unison.StdHSpacing,
+	})
+	statusBar.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, HGrab: true})
+	statusBar.SetBorder(unison.NewEmptyBorder(unison.Insets{Top: 2, Left: 2, Bottom: 2, Right: 2}))
+	statusPosition = newStatusLabel()
+	statusCounts = newStatusLabel()
+	// Everything after the counts is aligned right
+	statusCounts.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, HGrab: true})
+	statusModified = newStatusLabel()
+	statusLock = newStatusLabel()
+	statusEncoding = newStatusLabel()
+	statusEncoding.SetTitle(assets.TxtStatusEncoding)
+	statusFormat = newStatusLabel()
+	unison.InvokeTaskAfter(statusTick, statusInterval)
+	return statusBar
+}
+
+func newStatusLabel() *unison.Label {
+	lbl := unison.NewLabel()
+	lbl.Font = unison.LabelFont
+	statusBar.AddChild(lbl)
+	return lbl
+}
+
+func statusTick() {
+	updateStatusBar()
+	unison.InvokeTaskAfter(statusTick, statusInterval)
+}
+
+func updateStatusBar() {
+	changed := false
+	from, to := textEditor.Selection()
+	if statusDirty || from != statusCursor || to-from != statusSelection {
+		if from != statusCursor {
+			// The number of the current line is shown highlighted
+			if showLineNumbers {
+				lineNumbers.MarkForRedraw()
+			}
+		}
+		statusCursor, statusSelection = from, to-from
+		runes := []rune(textEditor.Text())
+		start := lineStart(runes, from)
+		position := fmt.Sprintf(assets.TxtStatusPosition, strings.Count(string(runes[:start]), "\n")+1, from-start+1)
+		if to > from {
+			position += fmt.Sprintf(assets.TxtStatusSelected, to-from)
+		}
+		changed = setStatus(statusPosition, position) || changed
+	}
+	if statusDirty {
+		statusDirty = false
+		text := textEditor.Text()
+		changed = setStatus(statusCounts, fmt.Sprintf(assets.TxtStatusCounts, utf8.RuneCountInString(text), len(strings.Fields(text)))) || changed
+	}
+	modified := ""
+	if isModified {
+		modified = assets.TxtStatusModified
+	}
+	changed = setStatus(statusModified, modified) || changed
+	lock := assets.CapUnlocked
+	switch {
+	case isReadOnly:
+		lock = assets.TxtStatusReadOnly
+	case isLocked:
+		lock = assets.CapLocked
+	}
+	changed = setStatus(statusLock, lock) || changed
+	changed = setStatus(statusFormat, fmt.Sprintf(assets.TxtStatusFormat, containerVersion)) || changed
+	if changed {
+		statusBar.MarkForLayoutAndRedraw()
+	}
+}
+
+func setStatus(lbl *unison.Label, title string) bool {
+	if lbl.String() == title {
+		return false
+	}
+	lbl.SetTitle(title)
+	return true
+}
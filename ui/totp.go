@@ -0,0 +1,152 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// One-time passwords: current codes of the otpauth URIs in the text and of the TOTP fields of records,
+// using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"fmt"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"strings"
+	"time"
+)
+
+type totpEntry struct {
+	otp       crypto.TOTP
+	code      *unison.Label
+	remaining *unison.ProgressBar
+	seconds   *unison.Label
+}
+
+var totpPanel *unison.Panel
+var totpEntries []totpEntry
+
+// Set when text or records change, the codes are looked up again with the next tick
+var totpDirty = false
+
+func createTOTPPanel() {
+	totpPanel = unison.NewPanel()
+	totpPanel.SetLayout(&unison.FlexLayout{
+		Columns:  5,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: 2,
+	})
+	totpPanel.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, HGrab: true})
+	unison.InvokeTaskAfter(totpTick, time.Second)
+}
+
+func totpTick() {
+	if totpDirty {
+		totpDirty = false
+		updateTOTPPanel()
+	}
+	now := time.Now()
+	for _, e := range totpEntries {
+		e.update(now)
+	}
+	if len(totpEntries) > 0 {
+		totpPanel.MarkForRedraw()
+	}
+	unison.InvokeTaskAfter(totpTick, time.Second)
+}
+
+// Names of fields holding a secret for one-time passwords
+func isTOTPField(name string) bool {
+	return strings.EqualFold(name, "totp") || strings.EqualFold(name, "otp")
+}
+
+// The panel is shown below the document while there are codes to show
+func updateTOTPPanel() {
+	totpPanel.RemoveAllChildren()
+	totpEntries = nil
+	for _, uri := range crypto.FindOTPAuth(textEditor.Text()) {
+		if otp, err := crypto.ParseTOTP(uri); err == nil {
+			addTOTPEntry(totpName(otp), otp)
+		}
+	}
+	for _, r := range docRecords {
+		for _, f := range r.Fields {
+			if !isTOTPField(f.Name) {
+				continue
+			}
+			if otp, err := crypto.ParseTOTP(f.Value); err == nil {
+				name := r.Title
+				if name == "" {
+					name = totpName(otp)
+				}
+				addTOTPEntry(name, otp)
+			}
+		}
+	}
+	content := mainWindow.Content()
+	if show := len(totpEntries) > 0; show != (totpPanel.Parent() != nil) {
+		if show {
+			content.AddChildAtIndex(totpPanel, content.IndexOfChild(workPanel)+1)
+		} else {
+			totpPanel.RemoveFromParent()
+		}
+	}
+	content.MarkForLayoutAndRedraw()
+}
+
+func totpName(otp crypto.TOTP) string {
+	switch {
+	case otp.Issuer != "" && otp.Account != "":
+		return fmt.Sprintf(assets.TxtTOTPName, otp.Issuer, otp.Account)
+	case otp.Issuer != "":
+		return otp.Issuer
+	case otp.Account != "":
+		return otp.Account
+	}
+	return assets.TxtTOTP
+}
+
+func addTOTPEntry(name string, otp crypto.TOTP) {
+	lbl := unison.NewLabel()
+	lbl.Font = unison.LabelFont
+	lbl.SetTitle(name)
+	lbl.SetLayoutData(&unison.FlexLayoutData{HAlign: align.Fill, VAlign: align.Middle, HGrab: true})
+	totpPanel.AddChild(lbl)
+	e := totpEntry{otp: otp}
+	e.code = unison.NewLabel()
+	e.code.Font = unison.MonospacedFont
+	e.code.SetLayoutData(&unison.FlexLayoutData{VAlign: align.Middle})
+	totpPanel.AddChild(e.code)
+	e.remaining = unison.NewProgressBar(float32(otp.Period()))
+	e.remaining.SetLayoutData(&unison.FlexLayoutData{MinSize: unison.Size{Width: 60}, VAlign: align.Middle})
+	totpPanel.AddChild(e.remaining)
+	e.seconds = unison.NewLabel()
+	e.seconds.Font = unison.LabelFont
+	e.seconds.SetLayoutData(&unison.FlexLayoutData{VAlign: align.Middle})
+	totpPanel.AddChild(e.seconds)
+	copyButton := unison.NewButton()
+	copyButton.SetTitle(assets.CapCopy)
+	copyButton.SetFocusable(false)
+	// The code is computed again, it may have changed since it was shown
+	copyButton.ClickCallback = func() {
+		unison.GlobalClipboard.SetText(otp.Code(time.Now()))
+		clipboardCopied(true)
+	}
+	totpPanel.AddChild(copyButton)
+	e.update(time.Now())
+	totpEntries = append(totpEntries, e)
+}
+
+func (e totpEntry) update(now time.Time) {
+	r := e.otp.Remaining(now)
+	e.code.SetTitle(formatTOTPCode(e.otp.Code(now)))
+	e.remaining.SetCurrent(float32(r))
+	e.seconds.SetTitle(fmt.Sprintf(assets.TxtTOTPSeconds, r))
+}
+
+// 123 456, easier to read and type
+func formatTOTPCode(code string) string {
+	half := (len(code) + 1) / 2
+	return code[:half] + " " + code[half:]
+}
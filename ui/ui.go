@@ -9,6 +9,7 @@ package ui
 import (
 	"SimpleTwofishEditor/assets"
 	"SimpleTwofishEditor/crypto"
+	"SimpleTwofishEditor/filelock"
 	"github.com/richardwilkes/unison"
 	"github.com/richardwilkes/unison/enums/align"
 	"github.com/richardwilkes/unison/enums/behavior"
@@ -18,6 +19,7 @@ import (
 	"io"
 	"os"
 	"path"
+	"slices"
 	"strconv"
 )
 
@@ -30,8 +32,39 @@ const (
 	FileNewActionID = unison.UserBaseID + iota
 	FileOpenActionID
 	FileSaveActionID
+	FileSaveAsActionID
+	FileRevertActionID
+	FileImportActionID
+	FileImportPlainActionID
+	FileExportPlainActionID
+	FilePropertiesActionID
+	FileAttachmentsActionID
+	FileNewVaultActionID
+	FileOpenVaultActionID
+	FileCloseVaultActionID
+	FileVaultMenuID
+	FileExportAgeActionID
+	FileExportPgpActionID
+	FileExportPgpTwofishActionID
+	FileExportMenuID
 	EditPasswordActionID
 	EditLockActionID
+	EditKeySlotsActionID
+	EditCopySensitiveActionID
+	ToolsGeneratorActionID
+	ToolsNewIdentityActionID
+	ToolsLoadIdentityActionID
+	ToolsCopyPublicKeyActionID
+	ToolsSearchActionID
+	EditRecordsActionID
+	EditPreviewActionID
+	EditSyntaxMenuID
+	ViewLineNumbersActionID
+	ViewWordWrapActionID
+	ViewMenuID
+	ToolsMenuID
+	// Followed by one action for each language, keep it last
+	EditSyntaxActionID
 )
 
 const (
@@ -51,6 +84,8 @@ var (
 	pasteBtn    *unison.Button
 )
 var textEditor *unison.Field
+var editorScroller *unison.ScrollPanel
+var workPanel *unison.Panel
 var (
 	fontNameMenu *unison.PopupMenu[string]
 	fontSizeMenu *unison.PopupMenu[string]
@@ -61,13 +96,40 @@ var isLocked = false
 var isModified = false
 var lastOpenFolder string
 var lastOpenFile = ""
+var pwdPolicy = defaultPasswordPolicy
+var genSettings = defaultGeneratorSettings
 
 var (
-	FileNewAction      *unison.Action
-	FileOpenAction     *unison.Action
-	FileSaveAction     *unison.Action
-	EditPasswordAction *unison.Action
-	EditLockAction     *unison.Action
+	FileNewAction              *unison.Action
+	FileOpenAction             *unison.Action
+	FileSaveAction             *unison.Action
+	FileSaveAsAction           *unison.Action
+	FileRevertAction           *unison.Action
+	FileImportAction           *unison.Action
+	FileImportPlainAction      *unison.Action
+	FileExportPlainAction      *unison.Action
+	FilePropertiesAction       *unison.Action
+	FileAttachmentsAction      *unison.Action
+	FileNewVaultAction         *unison.Action
+	FileOpenVaultAction        *unison.Action
+	FileCloseVaultAction       *unison.Action
+	FileExportAgeAction        *unison.Action
+	FileExportPgpAction        *unison.Action
+	FileExportPgpTwofishAction *unison.Action
+	EditPasswordAction         *unison.Action
+	EditLockAction             *unison.Action
+	EditKeySlotsAction         *unison.Action
+	EditCopySensitiveAction    *unison.Action
+	ToolsGeneratorAction       *unison.Action
+	ToolsNewIdentityAction     *unison.Action
+	ToolsLoadIdentityAction    *unison.Action
+	ToolsCopyPublicKeyAction   *unison.Action
+	ToolsSearchAction          *unison.Action
+	EditRecordsAction          *unison.Action
+	EditPreviewAction          *unison.Action
+	EditSyntaxActions          []*unison.Action
+	ViewLineNumbersAction      *unison.Action
+	ViewWordWrapAction         *unison.Action
 )
 
 func NewMainWindow() error {
@@ -89,8 +151,26 @@ func NewMainWindow() error {
 	})
 	// Create toolbar buttons
 	content.AddChild(createToolbarPanel())
-	// Create editor
-	content.AddChild(createEditorPanel())
+	// Create vault panel & editor side by side, the vault panel is added when a vault is opened
+	workPanel = unison.NewPanel()
+	workPanel.SetLayout(&unison.FlexLayout{
+		Columns:  1,
+		HSpacing: 5,
+		VSpacing: 5,
+	})
+	workPanel.SetLayoutData(&unison.FlexLayoutData{
+		HAlign: align.Fill,
+		VAlign: align.Fill,
+		HGrab:  true,
+		VGrab:  true,
+	})
+	createVaultPanel()
+	createRecordsPanel()
+	createPreviewPanel()
+	workPanel.AddChild(createEditorPanel())
+	content.AddChild(workPanel)
+	createTOTPPanel()
+	content.AddChild(createStatusBar())
 	prepareTitleIcon()
 	if len(titleIcons) > 0 {
 		mainWindow.SetTitleIcons(titleIcons)
@@ -121,6 +201,16 @@ func NewMainWindow() error {
 	if lastOpenFolder == "" {
 		lastOpenFolder, _ = os.UserHomeDir()
 	}
+	pwdPolicy = prefs.Policy
+	genSettings = prefs.Generator
+	clipboardTimeout = prefs.ClipboardTimeout
+	identityFile = prefs.IdentityFile
+	compressDocuments = prefs.Compress
+	setCompression()
+	showPreview = prefs.Preview
+	setLineNumbers(prefs.LineNumbers)
+	wordWrap = !prefs.NoWordWrap
+	updateWordWrap()
 	// Set font family & size
 	fontName = prefs.FontName
 	fontSize = prefs.FontSize
@@ -226,9 +316,12 @@ func createEditorPanel() *unison.Panel {
 	textEditor = unison.NewMultiLineField()
 	textEditor.SetWrap(true)
 	textEditor.AutoScroll = false
+	textEditor.DrawCallback = drawEditor
 	_, prefSize, _ := textEditor.Sizes(unison.Size{})
 	textEditor.SetFrameRect(unison.Rect{Size: prefSize})
+	createLineNumbers()
 	scroller := unison.NewScrollPanel()
+	editorScroller = scroller
 	//Follow: disable hor. scrolling, Fill: enable vert. scrolling
 	scroller.SetContent(textEditor, behavior.Follow, behavior.Fill)
 	scroller.SetLayoutData(&unison.FlexLayoutData{
@@ -239,6 +332,7 @@ func createEditorPanel() *unison.Panel {
 		VGrab:    true,
 	})
 	unison.InstallDefaultFieldBorder(textEditor, scroller)
+	editorPanel = scroller.AsPanel()
 	scroller.MouseWheelCallback = func(where, delta unison.Point, mod unison.Modifiers) bool {
 		b := scroller.DefaultMouseWheel(where, delta, mod)
 		if b {
@@ -253,7 +347,14 @@ func createEditorPanel() *unison.Panel {
 		}
 		return b
 	}
-	return scroller.AsPanel()
+	return editorPanel
+}
+
+// The field draws the text, syntax colors and the current line are drawn over it
+func drawEditor(gc *unison.Canvas, dirty unison.Rect) {
+	textEditor.DefaultDraw(gc, dirty)
+	drawSyntax(gc, dirty)
+	drawCurrentLine(gc)
 }
 
 func setCallbacks() {
@@ -278,7 +379,9 @@ func setCallbacks() {
 		return textEditorRuneTypedCallback(ch)
 	}
 	textEditor.RemoveCmdHandler(unison.CutItemID)
-	textEditor.InstallCmdHandlers(unison.CutItemID, func(_ any) bool { return textEditorCanCutOverride() }, func(_ any) { textEditor.Cut() })
+	textEditor.InstallCmdHandlers(unison.CutItemID, func(_ any) bool { return textEditorCanCutOverride() }, func(_ any) { editCut() })
+	textEditor.RemoveCmdHandler(unison.CopyItemID)
+	textEditor.InstallCmdHandlers(unison.CopyItemID, func(_ any) bool { return textEditor.CanCopy() }, func(_ any) { editCopy() })
 	textEditor.RemoveCmdHandler(unison.PasteItemID)
 	textEditor.InstallCmdHandlers(unison.PasteItemID, func(_ any) bool { return textEditorCanPasteOverride() }, func(_ any) { textEditor.Paste() })
 	textEditor.ModifiedCallback = func(before, after *unison.FieldState) {
@@ -311,6 +414,8 @@ func mainWindowAllowClose() bool {
 }
 
 func mainWindowWillClose() {
+	clearClipboard()
+	releaseLock()
 	savePreferences()
 }
 
@@ -344,11 +449,37 @@ func fileSave() {
 	actionSave()
 }
 
+func fileSaveAs() {
+	actionSaveAs()
+}
+
+func fileRevert() {
+	if lastOpenFile == "" {
+		return
+	}
+	if isModified {
+		if dialogToConfirm(assets.CapRevert, assets.MsgRevert, assets.MsgRevertDetail) != unison.ModalResponseOK {
+			return
+		}
+	}
+	actionRevert()
+}
+
 func editPassword() {
 	ShowPasswordDialog(PwdSet)
 }
 
+func toolsGenerator() {
+	password := ShowGeneratorDialog(assets.CapInsert, !isLocked)
+	if password != "" && !isLocked {
+		insertText(password)
+	}
+}
+
 func editLock() {
+	if isReadOnly {
+		return
+	}
 	isLocked = !isLocked
 	setLock(isLocked)
 }
@@ -365,6 +496,11 @@ func setLock(locked bool) {
 	}
 	cutBtn.SetEnabled(!isLocked)
 	pasteBtn.SetEnabled(!isLocked)
+	showRecordDetail()
+	updateDocumentView()
+	if locked {
+		clearClipboard()
+	}
 	svg, _ := unison.NewSVGFromContentString(svgcontent)
 	if svg != nil {
 		lockBtn.Drawable = &unison.DrawableSVG{
@@ -407,10 +543,17 @@ func setEditorFont() {
 
 func editCopy() {
 	textEditor.Copy()
+	clipboardCopied(false)
+}
+
+func editCopySensitive() {
+	textEditor.Copy()
+	clipboardCopied(true)
 }
 
 func editCut() {
 	textEditor.Cut()
+	clipboardCopied(false)
 }
 
 func editPaste() {
@@ -419,6 +562,14 @@ func editPaste() {
 	}
 }
 
+// Replace the selection of the editor field, typed like keystrokes sharing one undo ID,
+// the text does not pass the clipboard
+func insertText(text string) {
+	for _, r := range text {
+		textEditor.DefaultRuneTyped(r)
+	}
+}
+
 // Editor field event handlers
 func textEditorKeyDownCallback(keyCode unison.KeyCode, mod unison.Modifiers, repeat bool) bool {
 	if !isLocked {
@@ -450,6 +601,39 @@ func textEditorCanPasteOverride() bool {
 
 func textEditorModifiedCallback(before, after *unison.FieldState) {
 	isModified = before.Text != after.Text
+	totpDirty = true
+	statusDirty = true
+	updateLineNumbers()
+	schedulePreview()
+}
+
+// The document area of the work panel shows the records, or the editor and/or the Markdown preview
+func updateDocumentView() {
+	var panels []*unison.Panel
+	switch {
+	case showRecords:
+		panels = []*unison.Panel{recordsPanel}
+	case showPreview:
+		panels = []*unison.Panel{editorPanel, previewPanel}
+	default:
+		panels = []*unison.Panel{editorPanel}
+	}
+	// The editor keeps the focus if nothing changes
+	current := workPanel.Children()
+	if vaultPanel.Parent() != nil {
+		current = current[1:]
+	}
+	if slices.Equal(current, panels) {
+		return
+	}
+	for _, p := range []*unison.Panel{editorPanel, recordsPanel, previewPanel} {
+		p.RemoveFromParent()
+	}
+	for _, p := range panels {
+		workPanel.AddChild(p)
+	}
+	workPanel.Layout().(*unison.FlexLayout).Columns = len(workPanel.Children())
+	mainWindow.Content().MarkForLayoutAndRedraw()
 }
 
 func windowMinMaxResizeCallback() (minSize, maxSize unison.Size) {
@@ -467,16 +651,22 @@ func AllowQuitCallback() bool {
 }
 
 func actionNew() {
-	mainWindow.SetTitle(assets.AppName + " - " + assets.UnnamedFile)
 	textEditor.SetText("")
 	lastOpenFile = ""
+	plainTextSource = ""
+	forgetOpenFile()
+	releaseLock()
 	isModified = false
 	setLock(false)
+	setReadOnly(false)
 	crypto.Invalidate() //force new password request
+	containerVersion = crypto.ContainerVersionLatest
+	loadRecords()
+	updateSyntax()
+	updateWordWrap()
 }
 
 func actionOpen() {
-	var openFile = ""
 	var p = ""
 	dialog := unison.NewOpenDialog()
 	dialog.SetCanChooseDirectories(false)
@@ -487,56 +677,191 @@ func actionOpen() {
 	if dialog.RunModal() == true {
 		p = dialog.Path()
 		if p != "" {
-			lastOpenFolder, openFile = path.Split(p)
-			file, err := os.Open(p)
-			if err != nil {
-				dialogToDisplaySystemError(assets.ErrFileOpen, err)
-				return
-			}
-			payload, err := io.ReadAll(file)
-			_ = file.Close()
-			if err != nil {
-				dialogToDisplaySystemError(assets.ErrFileRead, err)
-				return
-			}
-			if ShowPasswordDialog(PwdGet) == unison.ModalResponseOK {
-				clearText, message := crypto.DecryptPayload(payload)
-				if message == "" {
-					textEditor.SetText(clearText)
-					isModified = false
-					setLock(true)
-					textEditor.SetSelectionToStart()
-					lastOpenFile = openFile
-					mainWindow.SetTitle(assets.AppName + " - " + lastOpenFile)
-				} else {
-					dialogToDisplayErrorMessage(assets.ErrDecryptionError, message)
-				}
-			}
+			openDocument(p)
 		}
 	}
 }
 
+func openDocument(p string) {
+	payload, ok := readPayload(p)
+	if !ok {
+		return
+	}
+	readOnly := false
+	if info, locked := filelock.LockedByOther(p); locked {
+		switch dialogToReportLock(info) {
+		case unison.ModalResponseOK:
+			readOnly = true
+		case responseBreakLock:
+			filelock.Break(p)
+		default:
+			return
+		}
+	}
+	if unlockAndLoad(p, payload) {
+		lockOpenFile(p, readOnly)
+	}
+}
+
+func actionRevert() {
+	p := path.Join(lastOpenFolder, lastOpenFile)
+	payload, ok := readPayload(p)
+	if !ok {
+		return
+	}
+	// The password may have been changed since the file was saved
+	clearText, message := crypto.DecryptPayload(payload)
+	if message != "" {
+		unlockAndLoad(p, payload)
+		return
+	}
+	showDocument(p, payload, clearText)
+}
+
+// The open document keeps its key and password if the file cannot be opened, e.g. with a mistyped password
+func unlockAndLoad(p string, payload []byte) bool {
+	state := crypto.SaveState()
+	if unlockPayload(payload) && loadPayload(p, payload) {
+		state.Discard()
+		return true
+	}
+	state.Restore()
+	return false
+}
+
+// Ask for what the file needs: nothing for documents of the vault opened, the identity for files shared with
+// public keys only, otherwise password and/or keyfile
+func unlockPayload(payload []byte) bool {
+	if crypto.UnlocksWithVault(payload) {
+		return true
+	}
+	if crypto.HasRecipients(payload) && crypto.RequiredFactors(payload) == 0 {
+		return ensureIdentity(payload)
+	}
+	if crypto.UnlocksWithIdentity(payload) {
+		return true
+	}
+	return ShowPasswordDialogWithFactors(PwdGet, crypto.RequiredFactors(payload)) == unison.ModalResponseOK
+}
+
+func readPayload(p string) ([]byte, bool) {
+	file, err := os.Open(p)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrFileOpen, err)
+		return nil, false
+	}
+	payload, err := io.ReadAll(file)
+	_ = file.Close()
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrFileRead, err)
+		return nil, false
+	}
+	return payload, true
+}
+
+func loadPayload(p string, payload []byte) bool {
+	clearText, message := crypto.DecryptPayload(payload)
+	if message != "" {
+		dialogToDisplayErrorMessage(assets.ErrDecryptionError, message)
+		return false
+	}
+	showDocument(p, payload, clearText)
+	return true
+}
+
+func showDocument(p string, payload []byte, clearText string) {
+	lastOpenFolder, lastOpenFile = path.Split(p)
+	plainTextSource = ""
+	textEditor.SetText(clearText)
+	containerVersion = crypto.ContainerVersion(payload)
+	loadRecords()
+	updateSyntax()
+	updateWordWrap()
+	isModified = false
+	setLock(true)
+	textEditor.SetSelectionToStart()
+	updateWindowTitle()
+	rememberOpenFile(p, payload)
+}
+
 func actionSave() bool {
-	var saveFile = ""
-	var p = ""
-	if !crypto.IsValid() {
+	if isReadOnly {
+		return actionSaveAs()
+	}
+	if !crypto.HasDocumentKey() {
 		if ShowPasswordDialog(PwdSet) != unison.ModalResponseOK {
 			return false
 		}
 	}
 	if lastOpenFile == "" || lastOpenFolder == "" {
-		dialog := unison.NewSaveDialog()
-		dialog.SetInitialFileName(assets.UnnamedFileNoExt)
-		dialog.SetInitialDirectory(lastOpenFolder)
-		dialog.SetAllowedExtensions(assets.FileExtension)
-		if dialog.RunModal() == true {
-			p = dialog.Path()
-			lastOpenFolder, lastOpenFile = path.Split(p)
-		} else {
+		if !chooseSaveFile() {
 			return false
 		}
+	} else if !confirmOverwriteExternalChange() {
+		return false
+	}
+	return writePayload()
+}
+
+func actionSaveAs() bool {
+	folder, file := lastOpenFolder, lastOpenFile
+	if !chooseSaveFile() {
+		return false
+	}
+	// The original keeps its path and key slots if the copy is not written
+	credentials := crypto.SaveCredentials()
+	if askSaveAsPassword() && writePayload() {
+		credentials.Discard()
+		return true
+	}
+	lastOpenFolder, lastOpenFile = folder, file
+	credentials.Restore()
+	return false
+}
+
+func askSaveAsPassword() bool {
+	if !crypto.HasDocumentKey() {
+		return ShowPasswordDialog(PwdSet) == unison.ModalResponseOK
+	}
+	answer := dialogToAskNewPassword()
+	if answer == unison.ModalResponseCancel ||
+		(answer == unison.ModalResponseOK && ShowPasswordDialog(PwdSet) != unison.ModalResponseOK) {
+		return false
+	}
+	if answer == unison.ModalResponseOK {
+		// The copy must not open with the other passwords of the original
+		crypto.ResetKeySlots()
+	}
+	return true
+}
+
+func chooseSaveFile() bool {
+	dialog := unison.NewSaveDialog()
+	if lastOpenFile != "" {
+		dialog.SetInitialFileName(lastOpenFile)
+	} else {
+		dialog.SetInitialFileName(assets.UnnamedFileNoExt)
+	}
+	dialog.SetInitialDirectory(lastOpenFolder)
+	dialog.SetAllowedExtensions(assets.FileExtension)
+	if dialog.RunModal() == true {
+		// A file edited in another instance is not overwritten, a stale lock is removed
+		if info, locked := filelock.LockedByOther(dialog.Path()); locked {
+			if !info.IsStale() {
+				dialogToDisplayErrorMessage(assets.ErrFileWrite, info.Describe())
+				return false
+			}
+			filelock.Break(dialog.Path())
+		}
+		lastOpenFolder, lastOpenFile = path.Split(dialog.Path())
+		return true
 	}
-	saveFile = path.Join(lastOpenFolder, lastOpenFile)
+	return false
+}
+
+func writePayload() bool {
+	saveFile := path.Join(lastOpenFolder, lastOpenFile)
+	storeWordWrap()
 	cipherText, err := crypto.EncryptPayload([]byte(textEditor.Text()))
 	if err != nil {
 		dialogToDisplaySystemError(assets.ErrEncryptionError, err)
@@ -548,6 +873,26 @@ func actionSave() bool {
 		return false
 	}
 	isModified = false
-	mainWindow.SetTitle(assets.AppName + " - " + lastOpenFile)
+	containerVersion = crypto.ContainerVersionLatest
+	rememberOpenFile(saveFile, cipherText)
+	shredPlainTextSource()
+	if err = acquireLock(saveFile); err == nil {
+		setReadOnly(false)
+	} else {
+		updateWindowTitle()
+	}
 	return true
 }
+
+func updateWindowTitle() {
+	title := assets.UnnamedFile
+	if name, ok := vaultEntryTitle(); ok {
+		title = name
+	} else if lastOpenFile != "" {
+		title = lastOpenFile
+	}
+	if isReadOnly {
+		title += assets.TxtReadOnly
+	}
+	mainWindow.SetTitle(assets.AppName + " - " + title)
+}
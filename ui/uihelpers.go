@@ -8,8 +8,10 @@ package ui
 
 import (
 	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
 	"github.com/richardwilkes/unison"
 	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/check"
 )
 
 var registeredFonts []unison.FontFaceDescriptor
@@ -82,18 +84,60 @@ func createButton(title string, svgcontent string) (*unison.Button, error) {
 
 func installDefaultMenus(wnd *unison.Window) {
 	unison.DefaultMenuFactory().BarForWindow(wnd, func(m unison.Menu) {
-		unison.InsertStdMenus(m, AboutDialog, nil, nil)
+		unison.InsertStdMenus(m, AboutDialog, PreferencesDialog, nil)
 		fileMenu := m.Menu(unison.FileMenuID)
 		f := fileMenu.Factory()
 		fileMenu.InsertItem(0, FileNewAction.NewMenuItem(f))
 		fileMenu.InsertItem(1, FileOpenAction.NewMenuItem(f))
 		fileMenu.InsertItem(2, FileSaveAction.NewMenuItem(f))
-		fileMenu.InsertSeparator(3, true)
+		fileMenu.InsertItem(3, FileSaveAsAction.NewMenuItem(f))
+		fileMenu.InsertItem(4, FileRevertAction.NewMenuItem(f))
+		fileMenu.InsertSeparator(5, true)
+		fileMenu.InsertItem(6, FileImportAction.NewMenuItem(f))
+		exportMenu := f.NewMenu(FileExportMenuID, assets.CapExport, nil)
+		exportMenu.InsertItem(-1, FileExportAgeAction.NewMenuItem(f))
+		exportMenu.InsertItem(-1, FileExportPgpAction.NewMenuItem(f))
+		exportMenu.InsertItem(-1, FileExportPgpTwofishAction.NewMenuItem(f))
+		fileMenu.InsertItem(7, FileImportPlainAction.NewMenuItem(f))
+		fileMenu.InsertMenu(8, exportMenu)
+		fileMenu.InsertItem(9, FileExportPlainAction.NewMenuItem(f))
+		fileMenu.InsertSeparator(10, true)
+		fileMenu.InsertItem(11, FilePropertiesAction.NewMenuItem(f))
+		fileMenu.InsertItem(12, FileAttachmentsAction.NewMenuItem(f))
+		fileMenu.InsertSeparator(13, true)
+		vaultMenu := f.NewMenu(FileVaultMenuID, assets.CapVault, nil)
+		vaultMenu.InsertItem(-1, FileNewVaultAction.NewMenuItem(f))
+		vaultMenu.InsertItem(-1, FileOpenVaultAction.NewMenuItem(f))
+		vaultMenu.InsertItem(-1, FileCloseVaultAction.NewMenuItem(f))
+		fileMenu.InsertMenu(14, vaultMenu)
+		fileMenu.InsertSeparator(15, true)
 		editMenu := m.Menu(unison.EditMenuID)
 		e := editMenu.Factory()
+		editMenu.InsertItem(2, EditCopySensitiveAction.NewMenuItem(e))
 		editMenu.InsertSeparator(-1, true)
 		editMenu.InsertItem(-1, EditPasswordAction.NewMenuItem(e))
+		editMenu.InsertItem(-1, EditKeySlotsAction.NewMenuItem(e))
 		editMenu.InsertItem(-1, EditLockAction.NewMenuItem(e))
+		editMenu.InsertSeparator(-1, true)
+		editMenu.InsertItem(-1, EditRecordsAction.NewMenuItem(e))
+		editMenu.InsertItem(-1, EditPreviewAction.NewMenuItem(e))
+		syntaxMenu := e.NewMenu(EditSyntaxMenuID, assets.CapSyntax, nil)
+		for _, a := range EditSyntaxActions {
+			syntaxMenu.InsertItem(-1, a.NewMenuItem(e))
+		}
+		editMenu.InsertMenu(-1, syntaxMenu)
+		viewMenu := f.NewMenu(ViewMenuID, assets.CapView, nil)
+		viewMenu.InsertItem(-1, ViewLineNumbersAction.NewMenuItem(f))
+		viewMenu.InsertItem(-1, ViewWordWrapAction.NewMenuItem(f))
+		m.InsertMenu(m.Count()-2, viewMenu)
+		toolsMenu := f.NewMenu(ToolsMenuID, assets.CapTools, nil)
+		toolsMenu.InsertItem(-1, ToolsGeneratorAction.NewMenuItem(f))
+		toolsMenu.InsertItem(-1, ToolsSearchAction.NewMenuItem(f))
+		toolsMenu.InsertSeparator(-1, true)
+		toolsMenu.InsertItem(-1, ToolsNewIdentityAction.NewMenuItem(f))
+		toolsMenu.InsertItem(-1, ToolsLoadIdentityAction.NewMenuItem(f))
+		toolsMenu.InsertItem(-1, ToolsCopyPublicKeyAction.NewMenuItem(f))
+		m.InsertMenu(m.Count()-2, toolsMenu)
 	})
 }
 
@@ -122,6 +166,104 @@ func initMenuHandler() {
 			fileSave()
 		},
 	}
+	FileSaveAsAction = &unison.Action{
+		ID:         FileSaveAsActionID,
+		Title:      assets.CapSaveAs,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyS, Modifiers: unison.ShiftModifier | unison.OSMenuCmdModifier()},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileSaveAs()
+		},
+	}
+	FileRevertAction = &unison.Action{
+		ID:    FileRevertActionID,
+		Title: assets.CapRevert,
+		EnabledCallback: func(_ *unison.Action, _ any) bool {
+			return lastOpenFile != ""
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileRevert()
+		},
+	}
+	FileImportAction = &unison.Action{
+		ID:    FileImportActionID,
+		Title: assets.CapImport,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileImport(false)
+		},
+	}
+	FileImportPlainAction = &unison.Action{
+		ID:    FileImportPlainActionID,
+		Title: assets.CapImportPlain,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileImport(true)
+		},
+	}
+	FileExportPlainAction = &unison.Action{
+		ID:    FileExportPlainActionID,
+		Title: assets.CapExportPlain,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileExportPlain()
+		},
+	}
+	FilePropertiesAction = &unison.Action{
+		ID:    FilePropertiesActionID,
+		Title: assets.CapDocumentProperties,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			ShowPropertiesDialog()
+		},
+	}
+	FileAttachmentsAction = &unison.Action{
+		ID:    FileAttachmentsActionID,
+		Title: assets.CapAttachments,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			ShowAttachmentsDialog()
+		},
+	}
+	FileNewVaultAction = &unison.Action{
+		ID:    FileNewVaultActionID,
+		Title: assets.CapNewVault,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileNewVault()
+		},
+	}
+	FileOpenVaultAction = &unison.Action{
+		ID:    FileOpenVaultActionID,
+		Title: assets.CapOpenVault,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileOpenVault()
+		},
+	}
+	FileCloseVaultAction = &unison.Action{
+		ID:    FileCloseVaultActionID,
+		Title: assets.CapCloseVault,
+		EnabledCallback: func(_ *unison.Action, _ any) bool {
+			return crypto.VaultIsOpen()
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileCloseVault()
+		},
+	}
+	FileExportAgeAction = &unison.Action{
+		ID:    FileExportAgeActionID,
+		Title: assets.CapExportAge,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileExport(crypto.FormatAge, assets.AgeExtension)
+		},
+	}
+	FileExportPgpAction = &unison.Action{
+		ID:    FileExportPgpActionID,
+		Title: assets.CapExportPgp,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileExport(crypto.FormatOpenPGP, assets.PgpExtension)
+		},
+	}
+	FileExportPgpTwofishAction = &unison.Action{
+		ID:    FileExportPgpTwofishActionID,
+		Title: assets.CapExportPgpTwofish,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			fileExport(crypto.FormatOpenPGPTwofish, assets.PgpExtension)
+		},
+	}
 	EditPasswordAction = &unison.Action{
 		ID:         EditPasswordActionID,
 		Title:      assets.CapPassword,
@@ -130,6 +272,16 @@ func initMenuHandler() {
 			editPassword()
 		},
 	}
+	EditKeySlotsAction = &unison.Action{
+		ID:    EditKeySlotsActionID,
+		Title: assets.CapKeySlots,
+		EnabledCallback: func(_ *unison.Action, _ any) bool {
+			return !isReadOnly
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			ShowKeySlotsDialog()
+		},
+	}
 	EditLockAction = &unison.Action{
 		ID:         EditLockActionID,
 		Title:      assets.CapLockUnlock,
@@ -138,6 +290,114 @@ func initMenuHandler() {
 			lockBtn.Click()
 		},
 	}
+	EditRecordsAction = &unison.Action{
+		ID:         EditRecordsActionID,
+		Title:      assets.CapShowRecords,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyE, Modifiers: unison.OSMenuCmdModifier()},
+		EnabledCallback: func(_ *unison.Action, src any) bool {
+			if item, ok := src.(unison.MenuItem); ok {
+				item.SetCheckState(check.FromBool(showRecords))
+			}
+			return true
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			editRecords()
+		},
+	}
+	EditPreviewAction = &unison.Action{
+		ID:         EditPreviewActionID,
+		Title:      assets.CapMarkdownPreview,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyM, Modifiers: unison.ShiftModifier | unison.OSMenuCmdModifier()},
+		EnabledCallback: func(_ *unison.Action, src any) bool {
+			if item, ok := src.(unison.MenuItem); ok {
+				item.SetCheckState(check.FromBool(showPreview))
+			}
+			return !showRecords
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			editPreview()
+		},
+	}
+	EditSyntaxActions = newSyntaxActions()
+	ViewLineNumbersAction = &unison.Action{
+		ID:         ViewLineNumbersActionID,
+		Title:      assets.CapLineNumbers,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyL, Modifiers: unison.ShiftModifier | unison.OSMenuCmdModifier()},
+		EnabledCallback: func(_ *unison.Action, src any) bool {
+			if item, ok := src.(unison.MenuItem); ok {
+				item.SetCheckState(check.FromBool(showLineNumbers))
+			}
+			return true
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			editLineNumbers()
+		},
+	}
+	ViewWordWrapAction = &unison.Action{
+		ID:         ViewWordWrapActionID,
+		Title:      assets.CapWordWrap,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyZ, Modifiers: unison.OptionModifier},
+		EnabledCallback: func(_ *unison.Action, src any) bool {
+			if item, ok := src.(unison.MenuItem); ok {
+				item.SetCheckState(check.FromBool(isWrapped))
+			}
+			return !showRecords
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			editWordWrap()
+		},
+	}
+	EditCopySensitiveAction = &unison.Action{
+		ID:         EditCopySensitiveActionID,
+		Title:      assets.CapCopySensitive,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyC, Modifiers: unison.ShiftModifier | unison.OSMenuCmdModifier()},
+		EnabledCallback: func(_ *unison.Action, _ any) bool {
+			return textEditor.CanCopy()
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			editCopySensitive()
+		},
+	}
+	ToolsGeneratorAction = &unison.Action{
+		ID:         ToolsGeneratorActionID,
+		Title:      assets.CapGeneratorMenu,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyG, Modifiers: unison.OSMenuCmdModifier()},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			toolsGenerator()
+		},
+	}
+	ToolsSearchAction = &unison.Action{
+		ID:         ToolsSearchActionID,
+		Title:      assets.CapSearchMenu,
+		KeyBinding: unison.KeyBinding{KeyCode: unison.KeyF, Modifiers: unison.ShiftModifier | unison.OSMenuCmdModifier()},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			ShowSearchDialog()
+		},
+	}
+	ToolsNewIdentityAction = &unison.Action{
+		ID:    ToolsNewIdentityActionID,
+		Title: assets.CapNewIdentity,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			toolsNewIdentity()
+		},
+	}
+	ToolsLoadIdentityAction = &unison.Action{
+		ID:    ToolsLoadIdentityActionID,
+		Title: assets.CapLoadIdentity,
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			toolsLoadIdentity()
+		},
+	}
+	ToolsCopyPublicKeyAction = &unison.Action{
+		ID:    ToolsCopyPublicKeyActionID,
+		Title: assets.CapCopyPublicKey,
+		EnabledCallback: func(_ *unison.Action, _ any) bool {
+			return crypto.IdentityLoaded()
+		},
+		ExecuteCallback: func(_ *unison.Action, _ any) {
+			toolsCopyPublicKey()
+		},
+	}
 }
 
 func prepareTitleIcon() {
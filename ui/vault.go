@@ -0,0 +1,323 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Vault: a folder of documents opened with one password, browsed in a side panel, using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"SimpleTwofishEditor/crypto"
+	"errors"
+	"github.com/richardwilkes/unison"
+	"github.com/richardwilkes/unison/enums/align"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"os"
+	"path"
+)
+
+const vaultPanelWidth = 200
+
+var vaultFolder = ""
+var vaultPanel *unison.Panel
+var vaultList *unison.List[string]
+var vaultEntries []crypto.VaultEntry
+var renameEntryButton *unison.Button
+var deleteEntryButton *unison.Button
+
+func createVaultPanel() {
+	vaultPanel = unison.NewPanel()
+	vaultPanel.SetLayout(&unison.FlexLayout{
+		Columns:  1,
+		HSpacing: unison.StdHSpacing,
+		VSpacing: unison.StdVSpacing,
+	})
+	vaultPanel.SetLayoutData(&unison.FlexLayoutData{
+		MinSize: unison.Size{Width: vaultPanelWidth},
+		HAlign:  align.Fill,
+		VAlign:  align.Fill,
+		VGrab:   true,
+	})
+	vaultList = unison.NewList[string]()
+	vaultList.SetAllowMultipleSelection(false)
+	vaultList.NewSelectionCallback = func() { updateVaultButtons() }
+	vaultList.DoubleClickCallback = func() { openVaultEntry() }
+	scroller := unison.NewScrollPanel()
+	scroller.SetContent(vaultList, behavior.Fill, behavior.Fill)
+	scroller.SetLayoutData(&unison.FlexLayoutData{
+		HAlign: align.Fill,
+		VAlign: align.Fill,
+		HGrab:  true,
+		VGrab:  true,
+	})
+	vaultPanel.AddChild(scroller)
+	buttons := unison.NewPanel()
+	buttons.SetLayout(&unison.FlowLayout{HSpacing: unison.StdHSpacing})
+	newButton := unison.NewButton()
+	newButton.SetTitle(assets.CapNewEntry)
+	newButton.ClickCallback = func() { newVaultEntry() }
+	buttons.AddChild(newButton)
+	renameEntryButton = unison.NewButton()
+	renameEntryButton.SetTitle(assets.CapRenameEntry)
+	renameEntryButton.ClickCallback = func() { renameVaultEntry() }
+	buttons.AddChild(renameEntryButton)
+	deleteEntryButton = unison.NewButton()
+	deleteEntryButton.SetTitle(assets.CapDeleteEntry)
+	deleteEntryButton.ClickCallback = func() { deleteVaultEntry() }
+	buttons.AddChild(deleteEntryButton)
+	vaultPanel.AddChild(buttons)
+}
+
+// Hidden panels keep their space in a flex layout, so the panel is taken out of the window instead
+func showVaultPanel(show bool) {
+	if show != (vaultPanel.Parent() != nil) {
+		if show {
+			workPanel.AddChildAtIndex(vaultPanel, 0)
+		} else {
+			vaultPanel.RemoveFromParent()
+		}
+		workPanel.Layout().(*unison.FlexLayout).Columns = len(workPanel.Children())
+	}
+	if show {
+		updateVaultList()
+	}
+	mainWindow.Content().MarkForLayoutAndRedraw()
+}
+
+func updateVaultList() {
+	vaultEntries = crypto.VaultEntries()
+	vaultList.Clear()
+	current := currentVaultEntry()
+	for i, e := range vaultEntries {
+		vaultList.Append(e.Name)
+		if e.ID == current {
+			vaultList.Select(false, i)
+		}
+	}
+	vaultList.MarkForLayoutAndRedraw()
+	updateVaultButtons()
+}
+
+func updateVaultButtons() {
+	selected := vaultList.Selection.FirstSet() >= 0
+	renameEntryButton.SetEnabled(selected)
+	deleteEntryButton.SetEnabled(selected)
+}
+
+func selectedVaultEntry() (crypto.VaultEntry, bool) {
+	index := vaultList.Selection.FirstSet()
+	if index < 0 || index >= len(vaultEntries) {
+		return crypto.VaultEntry{}, false
+	}
+	return vaultEntries[index], true
+}
+
+// The id of the open document if it belongs to the vault
+func currentVaultEntry() string {
+	if !crypto.VaultIsOpen() || lastOpenFile == "" || path.Clean(lastOpenFolder) != path.Clean(vaultFolder) {
+		return ""
+	}
+	for _, e := range vaultEntries {
+		if crypto.VaultEntryFile(e.ID) == lastOpenFile {
+			return e.ID
+		}
+	}
+	return ""
+}
+
+func saveChangesFirst() bool {
+	answer := dialogToSaveChanges()
+	if answer == unison.ModalResponseCancel {
+		return false
+	}
+	if answer == unison.ModalResponseOK {
+		return actionSave()
+	}
+	return true
+}
+
+// The folder must not contain a vault, the password entered protects the master key
+func fileNewVault() {
+	if !saveChangesFirst() {
+		return
+	}
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(true)
+	dialog.SetCanChooseFiles(false)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	if dialog.RunModal() != true {
+		return
+	}
+	folder := dialog.Path()
+	manifest := path.Join(folder, assets.VaultFileName)
+	if _, err := os.Stat(manifest); !errors.Is(err, os.ErrNotExist) {
+		dialogToDisplayErrorMessage(assets.ErrVaultUpdate, assets.ErrVaultExists)
+		return
+	}
+	closeVault()
+	actionNew()
+	if ShowPasswordDialog(PwdSet) != unison.ModalResponseOK {
+		return
+	}
+	data, err := crypto.CreateVault()
+	// The credentials are not needed anymore, documents are opened with the master key
+	actionNew()
+	if err == nil {
+		err = os.WriteFile(manifest, data, 0644)
+	}
+	if err != nil {
+		crypto.CloseVault()
+		dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		return
+	}
+	vaultFolder = folder
+	showVaultPanel(true)
+}
+
+func fileOpenVault() {
+	if !saveChangesFirst() {
+		return
+	}
+	dialog := unison.NewOpenDialog()
+	dialog.SetCanChooseDirectories(false)
+	dialog.SetCanChooseFiles(true)
+	dialog.SetAllowsMultipleSelection(false)
+	dialog.SetInitialDirectory(lastOpenFolder)
+	dialog.SetAllowedExtensions(assets.VaultExtension)
+	if dialog.RunModal() != true {
+		return
+	}
+	payload, ok := readPayload(dialog.Path())
+	if !ok {
+		return
+	}
+	closeVault()
+	actionNew()
+	if !unlockPayload(payload) {
+		return
+	}
+	message := crypto.UnlockVault(payload)
+	actionNew()
+	if message != "" {
+		dialogToDisplayErrorMessage(assets.ErrDecryptionError, message)
+		return
+	}
+	vaultFolder, _ = path.Split(dialog.Path())
+	showVaultPanel(true)
+}
+
+func fileCloseVault() {
+	if !saveChangesFirst() {
+		return
+	}
+	if currentVaultEntry() != "" {
+		actionNew()
+	}
+	closeVault()
+}
+
+func closeVault() {
+	crypto.CloseVault()
+	vaultFolder = ""
+	vaultEntries = nil
+	showVaultPanel(false)
+}
+
+func writeVaultManifest() bool {
+	data, err := crypto.VaultManifest()
+	if err == nil {
+		err = os.WriteFile(path.Join(vaultFolder, assets.VaultFileName), data, 0644)
+	}
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		return false
+	}
+	return true
+}
+
+func openVaultEntry() {
+	e, ok := selectedVaultEntry()
+	if !ok || e.ID == currentVaultEntry() || !saveChangesFirst() {
+		return
+	}
+	openDocument(path.Join(vaultFolder, crypto.VaultEntryFile(e.ID)))
+}
+
+// A new entry is saved right away, so the vault never lists a missing document
+func newVaultEntry() {
+	name, ok := dialogToEnterText(assets.CapNewEntryTitle, assets.CapEntryName, "")
+	if !ok || !saveChangesFirst() {
+		return
+	}
+	e, err := crypto.AddVaultEntry(name)
+	if err != nil {
+		dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		return
+	}
+	actionNew()
+	if err = crypto.JoinVault(); err != nil {
+		_ = crypto.RemoveVaultEntry(e.ID)
+		dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		return
+	}
+	lastOpenFolder, lastOpenFile = path.Split(path.Join(vaultFolder, crypto.VaultEntryFile(e.ID)))
+	if !writePayload() {
+		_ = crypto.RemoveVaultEntry(e.ID)
+		actionNew()
+		return
+	}
+	writeVaultManifest()
+	updateVaultList()
+	updateWindowTitle()
+}
+
+func renameVaultEntry() {
+	e, ok := selectedVaultEntry()
+	if !ok {
+		return
+	}
+	name, ok := dialogToEnterText(assets.CapRenameEntryTitle, assets.CapEntryName, e.Name)
+	if !ok {
+		return
+	}
+	if err := crypto.RenameVaultEntry(e.ID, name); err != nil {
+		dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		return
+	}
+	writeVaultManifest()
+	updateVaultList()
+	updateWindowTitle()
+}
+
+func deleteVaultEntry() {
+	e, ok := selectedVaultEntry()
+	if !ok {
+		return
+	}
+	if dialogToConfirm(assets.CapDeleteEntry, assets.MsgDeleteEntry, assets.MsgDeleteEntryDetail) != unison.ModalResponseOK {
+		return
+	}
+	if e.ID == currentVaultEntry() {
+		actionNew()
+	}
+	if err := crypto.RemoveVaultEntry(e.ID); err != nil {
+		dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		return
+	}
+	if writeVaultManifest() {
+		if err := os.Remove(path.Join(vaultFolder, crypto.VaultEntryFile(e.ID))); err != nil && !errors.Is(err, os.ErrNotExist) {
+			dialogToDisplaySystemError(assets.ErrVaultUpdate, err)
+		}
+	}
+	updateVaultList()
+}
+
+// Documents of the vault are shown by name, their file names are random
+func vaultEntryTitle() (string, bool) {
+	if id := currentVaultEntry(); id != "" {
+		return crypto.VaultEntryName(id)
+	}
+	return "", false
+}
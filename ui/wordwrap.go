@@ -0,0 +1,73 @@
+//----------------------------------------------------------------------------------------------------------------------
+// (w) 2024 by Jan Buchholz
+// Word wrap of the editor, stored in the document properties, the preferences hold the default,
+// using Unison library (c) Richard A. Wilkes
+// https://github.com/richardwilkes/unison
+//----------------------------------------------------------------------------------------------------------------------
+
+package ui
+
+import (
+	"SimpleTwofishEditor/assets"
+	"github.com/richardwilkes/unison/enums/behavior"
+	"strconv"
+)
+
+// Default for documents without the property
+var wordWrap = true
+var isWrapped = true
+
+// Set if the wrap has been changed while the document was locked, it is stored when the document is saved
+var wrapPending = false
+
+// The choice is kept as default for documents to come, read-only documents are not modified
+func editWordWrap() {
+	wrap := !isWrapped
+	wordWrap = wrap
+	setWordWrap(wrap)
+	if isReadOnly {
+		return
+	}
+	wrapPending = true
+	if !isLocked {
+		storeWordWrap()
+	}
+}
+
+func storeWordWrap() {
+	if wrapPending {
+		wrapPending = false
+		setDocumentProperty(assets.WordWrapProperty, strconv.FormatBool(isWrapped))
+	}
+}
+
+func updateWordWrap() {
+	wrapPending = false
+	wrap := wordWrap
+	if value, ok := documentProperty(assets.WordWrapProperty); ok {
+		if b, err := strconv.ParseBool(value); err == nil {
+			wrap = b
+		}
+	}
+	setWordWrap(wrap)
+}
+
+// Long lines are scrolled horizontally without wrap
+func setWordWrap(wrap bool) {
+	if wrap == isWrapped {
+		return
+	}
+	isWrapped = wrap
+	textEditor.SetWrap(wrap)
+	width := behavior.Fill
+	if wrap {
+		width = behavior.Follow
+	}
+	// The editor is added to the scroller again, it keeps the focus
+	focused := textEditor.Focused()
+	editorScroller.SetContent(textEditor, width, behavior.Fill)
+	if focused {
+		textEditor.RequestFocus()
+	}
+	editorPanel.MarkForLayoutAndRedraw()
+}